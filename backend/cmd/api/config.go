@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// configCommand implements "config print [-redacted] [flags]". It prints the
+// effective configuration as YAML, then any validation errors, and exits
+// non-zero if there are some.
+func configCommand(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: api config print [-redacted] [-config file] [flags]")
+		return 2
+	}
+	fs := flag.NewFlagSet("config print", flag.ContinueOnError)
+	redact := fs.Bool("redacted", false, "replace secrets with a placeholder")
+	cfg, err := config.Load(fs, args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		return 2
+	}
+
+	shown := cfg
+	if *redact {
+		shown = cfg.Redacted()
+	}
+	out, err := yaml.Marshal(shown)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	os.Stdout.Write(out)
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		return 1
+	}
+	return 0
+}
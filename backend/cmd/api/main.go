@@ -2,57 +2,148 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // leaderboard.time_zone must resolve without system zoneinfo
 
 	"github.com/ISKOnnect/iskonnect-web/internal/api"
 	"github.com/ISKOnnect/iskonnect-web/internal/config"
 	"github.com/ISKOnnect/iskonnect-web/internal/database"
+	"github.com/ISKOnnect/iskonnect-web/internal/email"
+	"github.com/ISKOnnect/iskonnect-web/internal/health"
+	"github.com/ISKOnnect/iskonnect-web/internal/logging"
+	"github.com/ISKOnnect/iskonnect-web/internal/metrics"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+	"github.com/ISKOnnect/iskonnect-web/internal/retention"
+	"github.com/ISKOnnect/iskonnect-web/internal/storage"
+	"github.com/ISKOnnect/iskonnect-web/internal/tracing"
+	"github.com/ISKOnnect/iskonnect-web/internal/utils"
 	"github.com/joho/godotenv"
 )
 
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found")
+	envErr := godotenv.Load()
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(configCommand(os.Args[2:]))
 	}
 
-	cfg := config.New()
-	db, err := database.Connect(cfg.Database)
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+	logger := logging.New(cfg.Server, os.Stdout)
+	slog.SetDefault(logger)
+	if envErr != nil {
+		logger.Info("no .env file found")
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, logger)
 	if err != nil {
-		log.Fatalf("Database connection failed: %v", err)
+		logger.Error("tracing setup failed", "error", err)
+		os.Exit(1)
+	}
+
+	db, err := database.Connect(cfg.Database, logger)
+	if err != nil {
+		logger.Error("database connection failed", "error", err)
+		os.Exit(1)
 	}
 	defer db.Close()
+	if err := database.Migrate(context.Background(), db, logger); err != nil {
+		logger.Error("database migration failed", "error", err)
+		os.Exit(1)
+	}
+
+	repos := models.NewRepositories(db, models.QueryOptions{
+		Timeout:       time.Duration(cfg.Database.QueryTimeoutSeconds) * time.Second,
+		SlowThreshold: time.Duration(cfg.Database.SlowQueryMillis) * time.Millisecond,
+		Logger:        logger,
+	})
 
-	router := api.New(db, cfg)
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go retention.NewJob(repos.Retention, cfg.Retention, storage.NewLocal(cfg.Storage).Remove, logger).Run(jobCtx)
+
+	// Requests derive their context from baseCtx, so cancelling it once the
+	// shutdown deadline passes aborts queries still in flight.
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
+	appMetrics := metrics.New(db)
+	var metricsServer *http.Server
+	if cfg.Metrics.ListenAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", appMetrics.Handler(cfg.Metrics.Token))
+		metricsServer = &http.Server{Addr: cfg.Metrics.ListenAddr, Handler: mux}
+		go func() {
+			logger.Info("metrics server running", "addr", cfg.Metrics.ListenAddr)
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("metrics server failed", "error", err)
+			}
+		}()
+	}
+
+	keys, err := utils.NewKeyring(cfg.JWT)
+	if err != nil {
+		logger.Error("loading JWT keys failed", "error", err)
+		os.Exit(1)
+	}
+
+	checker := health.NewChecker(db, cfg)
+	outbox := &email.Outbox{}
+	router := api.New(repos, cfg, keys, logger, appMetrics, checker, outbox)
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%s", cfg.Server.Port),
 		Handler:      router,
 		ReadTimeout:  time.Duration(cfg.Server.ReadTimeoutSeconds) * time.Second,
 		WriteTimeout: time.Duration(cfg.Server.WriteTimeoutSeconds) * time.Second,
 		IdleTimeout:  time.Duration(cfg.Server.IdleTimeoutSeconds) * time.Second,
+		BaseContext:  func(net.Listener) context.Context { return baseCtx },
 	}
 
 	go func() {
-		log.Printf("Server running on :%s", cfg.Server.Port)
+		logger.Info("server running", "port", cfg.Server.Port)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed: %v", err)
+			logger.Error("server failed", "error", err)
+			os.Exit(1)
 		}
 	}()
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Shutting down server...")
+	logger.Info("shutting down server")
+	checker.Drain()
+	time.Sleep(time.Duration(cfg.Health.DrainSeconds) * time.Second)
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeoutSeconds)*time.Second)
 	defer cancel()
+	if metricsServer != nil {
+		metricsServer.Shutdown(ctx)
+	}
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Shutdown failed: %v", err)
+		cancelRequests()
+		logger.Error("shutdown failed", "error", err)
+		os.Exit(1)
+	}
+	// Requests are done; wait for the notifications they queued.
+	if err := outbox.Wait(ctx); err != nil {
+		logger.Error("emails still sending at shutdown", "error", err)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("tracing shutdown failed", "error", err)
 	}
-	log.Println("Server stopped")
-}
\ No newline at end of file
+	logger.Info("server stopped")
+}
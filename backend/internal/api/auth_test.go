@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/audit"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+// TestDemotedModeratorLosesAccess checks that a session issued while the
+// user was a moderator stops working once they are demoted.
+func TestDemotedModeratorLosesAccess(t *testing.T) {
+	router, store, keys := newTestRouter(t)
+	repos := store.Repositories()
+	ctx := context.Background()
+	u := newStudent(t, repos, 1)
+	if err := repos.Users.SetModerator(ctx, u.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	moderator, err := repos.Users.GetByID(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := keys.GenerateJWT(moderator, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := do(t, router, http.MethodGet, "/api/v1/moderation/reports", token, "", nil); rec.Code != http.StatusOK {
+		t.Fatalf("reports as moderator = %d: %s", rec.Code, rec.Body)
+	}
+	if err := repos.Users.SetModerator(ctx, u.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if rec := do(t, router, http.MethodGet, "/api/v1/moderation/reports", token, "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("reports after demotion = %d, want 401: %s", rec.Code, rec.Body)
+	}
+}
+
+// TestAuditRecordsClientIP checks that audit events store the client address
+// without its port, taking it from the proxy headers when present.
+func TestAuditRecordsClientIP(t *testing.T) {
+	router, store, keys := newTestRouter(t)
+	repos := store.Repositories()
+	u := newStudent(t, repos, 1)
+	token, err := keys.GenerateJWT(u, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		forwardedFor string
+		want         string
+	}{
+		{"", "192.0.2.1"},
+		{"203.0.113.9", "203.0.113.9"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me/export", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set("Authorization", "Bearer "+token)
+		if tt.forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("export = %d: %s", rec.Code, rec.Body)
+		}
+
+		events, _, err := repos.Audit.List(context.Background(), models.AuditFilter{Action: audit.ActionUserExport, Limit: 10})
+		if err != nil || len(events) == 0 {
+			t.Fatalf("audit events = %v, %v", events, err)
+		}
+		if got := events[0].IP; got != tt.want {
+			t.Errorf("X-Forwarded-For %q: audit IP = %q, want %q", tt.forwardedFor, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/api/middleware"
+)
+
+// TestCookieRoutesNeedCSRF checks that logout and refresh, which act on the
+// refresh_token cookie, refuse requests without the double-submit header.
+func TestCookieRoutesNeedCSRF(t *testing.T) {
+	router, store, _ := newTestRouter(t)
+	u := newStudent(t, store.Repositories(), 1)
+	body := `{"student_number": "` + u.StudentNumber + `", "password": "` + testPassword + `"}`
+	login := do(t, router, http.MethodPost, "/api/v1/auth/login", "", body, nil)
+	if login.Code != http.StatusOK {
+		t.Fatalf("login = %d: %s", login.Code, login.Body)
+	}
+	cookies := login.Result().Cookies()
+	var csrf string
+	for _, c := range cookies {
+		if c.Name == middleware.CSRFCookie {
+			csrf = c.Value
+		}
+	}
+
+	for _, path := range []string{"/api/v1/auth/refresh", "/api/v2/auth/logout"} {
+		for _, header := range []string{"", "forged", csrf} {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(""))
+			for _, c := range cookies {
+				req.AddCookie(c)
+			}
+			if header != "" {
+				req.Header.Set(middleware.CSRFHeader, header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			want := http.StatusForbidden
+			if header == csrf {
+				want = http.StatusOK
+			}
+			if rec.Code != want {
+				t.Errorf("POST %s with header %q = %d, want %d: %s", path, header, rec.Code, want, rec.Body)
+			}
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/api/dto"
+	"github.com/ISKOnnect/iskonnect-web/internal/api/handlers"
+	"github.com/ISKOnnect/iskonnect-web/internal/api/openapi"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+	"github.com/ISKOnnect/iskonnect-web/internal/utils"
+)
+
+// Response bodies that handlers write as maps are described here so the
+// document can name them.
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+type LoginResponse struct {
+	User         dto.SelfUser `json:"user"`
+	AccessToken  string       `json:"access_token"`
+	RefreshToken string       `json:"refresh_token"`
+	CSRFToken    string       `json:"csrf_token"`
+}
+
+type LoginResponseV2 struct {
+	User         dto.User `json:"user"`
+	AccessToken  string   `json:"access_token"`
+	RefreshToken string   `json:"refresh_token"`
+	CSRFToken    string   `json:"csrf_token"`
+}
+
+type RefreshResponse struct {
+	AccessToken string `json:"access_token"`
+	CSRFToken   string `json:"csrf_token"`
+}
+
+type PasswordChangedResponse struct {
+	Message      string `json:"message"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	CSRFToken    string `json:"csrf_token"`
+}
+
+type ResetTokenResponse struct {
+	ResetToken string `json:"reset_token"`
+}
+
+type UploadResponse struct {
+	Material models.Material `json:"material"`
+	User     dto.SelfUser    `json:"user"`
+}
+
+type UploadResponseV2 struct {
+	Material models.Material `json:"material"`
+	User     dto.User        `json:"user"`
+}
+
+type LeaderboardResponse struct {
+	Window  string                     `json:"window"`
+	College string                     `json:"college"`
+	Course  string                     `json:"course"`
+	Entries []*models.LeaderboardEntry `json:"entries"`
+	Me      *models.LeaderboardEntry   `json:"me"`
+}
+
+type CommentPage struct {
+	Comments []*models.Comment `json:"comments"`
+	Total    int               `json:"total"`
+	Limit    int               `json:"limit"`
+	Offset   int               `json:"offset"`
+}
+
+type ReportPage struct {
+	Reports []*models.Report `json:"reports"`
+	Total   int              `json:"total"`
+	Limit   int              `json:"limit"`
+	Offset  int              `json:"offset"`
+}
+
+type AuditPage struct {
+	Events []*models.AuditEvent `json:"events"`
+	Total  int                  `json:"total"`
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
+}
+
+type MaterialPage struct {
+	Items  []*models.Material `json:"items"`
+	Total  int                `json:"total"`
+	Limit  int                `json:"limit"`
+	Offset int                `json:"offset"`
+}
+
+type UserPage struct {
+	Items  []*dto.AdminUser `json:"items"`
+	Total  int              `json:"total"`
+	Limit  int              `json:"limit"`
+	Offset int              `json:"offset"`
+}
+
+type ReportCreatedResponse struct {
+	ID      int    `json:"id"`
+	Message string `json:"message"`
+}
+
+type BulkActionResponse struct {
+	Action    string         `json:"action"`
+	Processed []int          `json:"processed"`
+	Failed    map[int]string `json:"failed"`
+}
+
+type HealthCheck struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type HealthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+var (
+	pagination = []openapi.Param{
+		{Name: "limit", Type: "integer", Description: "Page size"},
+		{Name: "offset", Type: "integer", Description: "Items to skip"},
+	}
+	auditFilter = append([]openapi.Param{
+		{Name: "actor_id", Type: "integer"},
+		{Name: "action", Type: "string"},
+		{Name: "target_type", Type: "string"},
+		{Name: "target_id", Type: "integer"},
+		{Name: "since", Type: "string", Description: "RFC 3339 timestamp"},
+		{Name: "until", Type: "string", Description: "RFC 3339 timestamp"},
+	}, pagination...)
+)
+
+// routeDocs describes every route registered in New, keyed by its
+// unversioned path; apiDocs derives the versioned entries. A route missing
+// from the result is logged when the router is built.
+var routeDocs = openapi.Spec{
+	"GET /healthz":               {Summary: "Liveness probe", Tag: "health", Public: true, Response: HealthResponse{}},
+	"GET /readyz":                {Summary: "Readiness probe", Tag: "health", Public: true, Response: HealthResponse{}},
+	"GET /.well-known/jwks.json": {Summary: "Public keys that verify access tokens", Tag: "auth", Public: true, Response: utils.JWKS{}},
+	"GET /metrics":               {Summary: "Prometheus metrics (bearer token from METRICS_TOKEN)", Tag: "health", ContentTypes: []string{"text/plain"}},
+
+	"GET /api/openapi.json": {Summary: "This document", Tag: "docs", Public: true},
+	"GET /api/docs":         {Summary: "API reference UI", Tag: "docs", Public: true, ContentTypes: []string{"text/html"}},
+
+	"POST /api/auth/register":        {Summary: "Register a student account", Tag: "auth", Public: true, Body: handlers.RegisterRequest{}, Status: http.StatusCreated, Response: MessageResponse{}},
+	"GET /api/auth/confirm-email":    {Summary: "Confirm an email change", Tag: "auth", Public: true, Query: []openapi.Param{{Name: "token", Type: "string"}}, Response: MessageResponse{}},
+	"GET /api/auth/verify-email":     {Summary: "Verify an email address", Tag: "auth", Public: true, Query: []openapi.Param{{Name: "token", Type: "string"}}, Response: MessageResponse{}},
+	"POST /api/auth/login":           {Summary: "Log in", Tag: "auth", Public: true, Personal: true, Body: handlers.LoginRequest{}, Response: LoginResponse{}},
+	"POST /api/auth/logout":          {Summary: "Log out and revoke the refresh token", Tag: "auth", Public: true, Response: MessageResponse{}},
+	"POST /api/auth/refresh":         {Summary: "Exchange the refresh cookie for an access token", Tag: "auth", Public: true, Response: RefreshResponse{}},
+	"POST /api/auth/forgot-password": {Summary: "Email a password reset OTP", Tag: "auth", Public: true, Body: handlers.ForgotPasswordRequest{}, Response: MessageResponse{}},
+	"POST /api/auth/verify-otp":      {Summary: "Exchange a reset OTP for a reset token", Tag: "auth", Public: true, Body: handlers.VerifyOTPRequest{}, Response: ResetTokenResponse{}},
+	"POST /api/auth/reset-password":  {Summary: "Set a new password with a reset token and sign out everywhere", Tag: "auth", Public: true, Body: handlers.ResetPasswordRequest{}, Response: MessageResponse{}},
+
+	"GET /api/users/me":                {Summary: "Current user", Tag: "users", Personal: true, Response: dto.SelfUser{}},
+	"PUT /api/users/me":                {Summary: "Update the current user", Tag: "users", Personal: true, Body: handlers.ProfileUpdateRequest{}, Response: dto.SelfUser{}},
+	"DELETE /api/users/me":             {Summary: "Delete the current account after a grace period", Tag: "users", Body: handlers.DeleteAccountRequest{}, Status: http.StatusAccepted, Response: handlers.AccountDeletedResponse{}},
+	"GET /api/users/me/export":         {Summary: "Download a ZIP of your personal data", Tag: "users", ContentTypes: []string{"application/zip"}},
+	"GET /api/users/me/privacy":        {Summary: "Privacy settings", Tag: "users", Response: models.PrivacySettings{}},
+	"PUT /api/users/me/privacy":        {Summary: "Update privacy settings", Tag: "users", Body: models.PrivacySettings{}, Response: models.PrivacySettings{}},
+	"POST /api/users/me/password":      {Summary: "Change password and sign out other sessions", Tag: "users", Body: handlers.ChangePasswordRequest{}, Response: PasswordChangedResponse{}},
+	"POST /api/users/me/email":         {Summary: "Request an email change", Tag: "users", Body: handlers.ChangeEmailRequest{}, Status: http.StatusAccepted, Response: MessageResponse{}},
+	"GET /api/users/me/tokens":         {Summary: "Personal access tokens", Tag: "users", Response: []*models.APIToken{}},
+	"POST /api/users/me/tokens":        {Summary: "Create a personal access token", Tag: "users", Body: handlers.TokenCreateRequest{}, Status: http.StatusCreated, Response: handlers.TokenCreatedResponse{}},
+	"DELETE /api/users/me/tokens/{id}": {Summary: "Revoke a personal access token", Tag: "users", Response: MessageResponse{}},
+	"GET /api/users/{id}/profile":      {Summary: "Public profile", Tag: "users", Response: dto.PublicUser{}},
+
+	"GET /api/materials/":               {Summary: "List materials", Tag: "materials", Scope: models.ScopeMaterialsRead, Response: []*models.Material{}},
+	"POST /api/materials/":              {Summary: "Upload a material", Tag: "materials", Scope: models.ScopeMaterialsUpload, Personal: true, Body: models.Material{}, Status: http.StatusCreated, Response: UploadResponse{}},
+	"GET /api/materials/{id}":           {Summary: "Get a material", Tag: "materials", Scope: models.ScopeMaterialsRead, Response: models.Material{}},
+	"POST /api/materials/{id}/vote":     {Summary: "Vote on a material", Tag: "materials", Scope: models.ScopeMaterialsVote, Body: handlers.VoteRequest{}, Response: models.Material{}},
+	"POST /api/materials/{id}/bookmark": {Summary: "Bookmark a material", Tag: "materials", Status: http.StatusCreated, Response: MessageResponse{}},
+	"POST /api/materials/{id}/report":   {Summary: "Report a material", Tag: "moderation", Body: handlers.ReportRequest{}, Status: http.StatusCreated, Response: ReportCreatedResponse{}},
+	"GET /api/materials/bookmarks":      {Summary: "Bookmarked materials", Tag: "materials", Scope: models.ScopeMaterialsRead, Response: []*models.Material{}},
+	"GET /api/leaderboard": {Summary: "Points leaderboard", Tag: "materials", Response: LeaderboardResponse{}, Query: []openapi.Param{
+		{Name: "window", Type: "string", Description: "week, month, semester or all"},
+		{Name: "college", Type: "string"},
+		{Name: "course", Type: "string"},
+		{Name: "limit", Type: "integer"},
+	}},
+
+	"GET /api/materials/{id}/comments/":                    {Summary: "List top-level comments", Tag: "comments", Query: pagination, Response: CommentPage{}},
+	"POST /api/materials/{id}/comments/":                   {Summary: "Post a comment or reply", Tag: "comments", Body: handlers.CommentRequest{}, Status: http.StatusCreated, Response: models.Comment{}},
+	"PUT /api/materials/{id}/comments/{commentID}":         {Summary: "Edit a comment", Tag: "comments", Body: handlers.CommentRequest{}, Response: models.Comment{}},
+	"DELETE /api/materials/{id}/comments/{commentID}":      {Summary: "Delete a comment", Tag: "comments", Status: http.StatusNoContent},
+	"GET /api/materials/{id}/comments/{commentID}/replies": {Summary: "List replies", Tag: "comments", Query: pagination, Response: CommentPage{}},
+	"GET /api/materials/{id}/comments/{commentID}/history": {Summary: "Edit history", Tag: "comments", Response: []*models.CommentEdit{}},
+
+	"GET /api/moderation/reports": {Summary: "List reports", Tag: "moderation", Response: ReportPage{}, Query: append([]openapi.Param{
+		{Name: "status", Type: "string", Description: "open (default), reviewing, resolved, dismissed or all"},
+		{Name: "reason", Type: "string"},
+	}, pagination...)},
+	"PUT /api/moderation/reports/{id}": {Summary: "Change a report's status", Tag: "moderation", Body: handlers.ReportStatusRequest{}, Response: MessageResponse{}},
+	"POST /api/moderation/actions":     {Summary: "Apply an action to several reports", Tag: "moderation", Body: handlers.BulkActionRequest{}, Response: BulkActionResponse{}},
+
+	"GET /api/admin/users":                   {Summary: "List users", Tag: "admin", Response: []*dto.AdminUser{}},
+	"DELETE /api/admin/users/{id}":           {Summary: "Soft-delete a user", Tag: "admin", Status: http.StatusNoContent},
+	"GET /api/admin/users/deleted":           {Summary: "List deleted users", Tag: "admin", Response: []*dto.AdminUser{}},
+	"POST /api/admin/users/{id}/restore":     {Summary: "Restore a deleted user", Tag: "admin", Status: http.StatusNoContent},
+	"PUT /api/admin/users/{id}/moderator":    {Summary: "Grant or revoke moderator", Tag: "admin", Body: handlers.ModeratorRequest{}, Status: http.StatusNoContent},
+	"GET /api/admin/materials":               {Summary: "List all materials", Tag: "admin", Response: []*models.Material{}},
+	"PUT /api/admin/materials/{id}":          {Summary: "Edit a material", Tag: "admin", Body: models.Material{}, Response: models.Material{}},
+	"DELETE /api/admin/materials/{id}":       {Summary: "Soft-delete a material", Tag: "admin", Status: http.StatusNoContent},
+	"GET /api/admin/materials/deleted":       {Summary: "List deleted materials", Tag: "admin", Response: []*models.Material{}},
+	"POST /api/admin/materials/{id}/restore": {Summary: "Restore a deleted material", Tag: "admin", Status: http.StatusNoContent},
+	"GET /api/admin/audit":                   {Summary: "Search the audit log", Tag: "admin", Query: auditFilter, Response: AuditPage{}},
+	"GET /api/admin/audit/export": {Summary: "Export the audit log", Tag: "admin", ContentTypes: []string{"application/x-ndjson", "text/csv"},
+		Query: append(append([]openapi.Param{}, auditFilter...), openapi.Param{Name: "format", Type: "string", Description: "jsonl (default) or csv"})},
+}
+
+// v2Lists are the routes that return a Page in v2 instead of a bare array.
+var v2Lists = map[string]interface{}{
+	"GET /api/materials/":              MaterialPage{},
+	"GET /api/materials/bookmarks":     MaterialPage{},
+	"GET /api/admin/users":             UserPage{},
+	"GET /api/admin/users/deleted":     UserPage{},
+	"GET /api/admin/materials":         MaterialPage{},
+	"GET /api/admin/materials/deleted": MaterialPage{},
+}
+
+// v2Responses are the other routes whose response changes shape in v2.
+var v2Responses = map[string]interface{}{
+	"POST /api/auth/login": LoginResponseV2{},
+	"POST /api/materials/": UploadResponseV2{},
+}
+
+// apiDocs expands routeDocs to /api/v1 and /api/v2 and marks the
+// unversioned paths deprecated.
+func apiDocs() openapi.Spec {
+	spec := openapi.Spec{}
+	for key, route := range routeDocs {
+		method, path, _ := strings.Cut(key, " ")
+		if strings.HasPrefix(path, "/api/admin/") {
+			route.Personal = true
+		}
+		rest, ok := strings.CutPrefix(path, "/api/")
+		if !ok || rest == "openapi.json" || rest == "docs" {
+			spec[key] = route
+			continue
+		}
+		spec[method+" /api/v1/"+rest] = route
+
+		v2 := route
+		if page, ok := v2Lists[key]; ok {
+			v2.Response = page
+			v2.Query = pagination
+		}
+		if resp, ok := v2Responses[key]; ok {
+			v2.Response = resp
+		}
+		spec[method+" /api/v2/"+rest] = v2
+
+		route.Deprecated = true
+		spec[key] = route
+	}
+	return spec
+}
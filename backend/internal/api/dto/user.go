@@ -0,0 +1,123 @@
+// Package dto defines how users appear in API responses. models.User carries
+// every column; handlers map it to the view that matches who is asking so
+// that personal data never reaches other students by accident.
+package dto
+
+import (
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+// PIIFields are the JSON fields only the user themself and admins may see.
+var PIIFields = []string{"email", "student_number", "email_verified"}
+
+// SelfUser is a user's view of their own account.
+type SelfUser struct {
+	ID            int       `json:"id"`
+	StudentNumber string    `json:"student_number,omitempty"`
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	Email         string    `json:"email"`
+	College       string    `json:"college,omitempty"`
+	Course        string    `json:"course,omitempty"`
+	IsStudent     bool      `json:"is_student"`
+	IsModerator   bool      `json:"is_moderator"`
+	Points        int       `json:"points"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func NewSelfUser(u *models.User) *SelfUser {
+	return &SelfUser{
+		ID:            u.ID,
+		StudentNumber: u.StudentNumber,
+		FirstName:     u.FirstName,
+		LastName:      u.LastName,
+		Email:         u.Email,
+		College:       u.College,
+		Course:        u.Course,
+		IsStudent:     u.IsStudent,
+		IsModerator:   u.IsModerator,
+		Points:        u.Points,
+		EmailVerified: u.EmailVerified,
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+	}
+}
+
+// User is the v2 view of the account embedded in other responses, such as
+// the login and upload results. It leaves out PIIFields; users read their
+// own from /users/me.
+type User struct {
+	ID          int       `json:"id"`
+	FirstName   string    `json:"first_name"`
+	LastName    string    `json:"last_name"`
+	College     string    `json:"college,omitempty"`
+	Course      string    `json:"course,omitempty"`
+	IsStudent   bool      `json:"is_student"`
+	IsModerator bool      `json:"is_moderator"`
+	Points      int       `json:"points"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func NewUser(u *models.User) *User {
+	return &User{
+		ID:          u.ID,
+		FirstName:   u.FirstName,
+		LastName:    u.LastName,
+		College:     u.College,
+		Course:      u.Course,
+		IsStudent:   u.IsStudent,
+		IsModerator: u.IsModerator,
+		Points:      u.Points,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+	}
+}
+
+// AdminUser is the full record shown in admin tools. It has SelfUser's
+// fields; it is a type of its own so the document names it and so it can
+// grow fields users should not see about themselves.
+type AdminUser SelfUser
+
+func NewAdminUser(u *models.User) *AdminUser {
+	return (*AdminUser)(NewSelfUser(u))
+}
+
+func NewAdminUsers(users []*models.User) []*AdminUser {
+	out := make([]*AdminUser, len(users))
+	for i, u := range users {
+		out[i] = NewAdminUser(u)
+	}
+	return out
+}
+
+// PublicUser is what other users see of a user. The display name honors
+// the user's hide_real_name setting, and Uploads is empty when they hide
+// their activity.
+type PublicUser struct {
+	ID          int                `json:"id"`
+	DisplayName string             `json:"display_name"`
+	College     string             `json:"college,omitempty"`
+	Course      string             `json:"course,omitempty"`
+	Points      int                `json:"points"`
+	Badges      []*models.Badge    `json:"badges"`
+	Uploads     []*models.Material `json:"uploads,omitempty"`
+	MemberSince time.Time          `json:"member_since"`
+}
+
+func NewPublicUser(p *models.PublicProfile) *PublicUser {
+	return &PublicUser{
+		ID:          p.ID,
+		DisplayName: p.DisplayName,
+		College:     p.College,
+		Course:      p.Course,
+		Points:      p.Points,
+		Badges:      p.Badges,
+		Uploads:     p.Uploads,
+		MemberSince: p.MemberSince,
+	}
+}
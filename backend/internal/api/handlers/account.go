@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/api/dto"
+	"github.com/ISKOnnect/iskonnect-web/internal/api/middleware"
+	"github.com/ISKOnnect/iskonnect-web/internal/api/render"
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+	"github.com/ISKOnnect/iskonnect-web/internal/audit"
+	"github.com/ISKOnnect/iskonnect-web/internal/config"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+	"github.com/ISKOnnect/iskonnect-web/internal/service"
+)
+
+type AccountHandler struct {
+	accounts *service.AccountService
+	cookie   config.CookieConfig
+	auditLog *audit.Logger
+}
+
+func NewAccountHandler(accounts *service.AccountService, cookie config.CookieConfig, auditLog *audit.Logger) *AccountHandler {
+	return &AccountHandler{accounts: accounts, cookie: cookie, auditLog: auditLog}
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+type AccountDeletedResponse struct {
+	Message    string    `json:"message"`
+	PurgeAfter time.Time `json:"purge_after"`
+}
+
+// ExportProfile is profile.json in the export.
+type ExportProfile struct {
+	User    *dto.SelfUser           `json:"user"`
+	Privacy *models.PrivacySettings `json:"privacy"`
+}
+
+// Export streams a ZIP with one JSON file per kind of personal data.
+func (h *AccountHandler) Export(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	export, err := h.accounts.Export(r.Context(), userID)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	h.auditLog.Record(r, audit.Entry{
+		ActorID:    userID,
+		Action:     audit.ActionUserExport,
+		TargetType: "user",
+		TargetID:   userID,
+	})
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="iskonnect-export-%d.zip"`, userID))
+	w.Header().Set("Cache-Control", "no-store")
+	zw := zip.NewWriter(w)
+	for _, file := range []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", ExportProfile{User: dto.NewSelfUser(export.User), Privacy: export.Privacy}},
+		{"uploads.json", export.Uploads},
+		{"votes.json", export.Votes},
+		{"bookmarks.json", export.Bookmarks},
+		{"badges.json", export.Badges},
+		{"points.json", export.Points},
+	} {
+		f, err := zw.Create(file.name)
+		if err != nil {
+			// The status line is already sent; a truncated archive is all
+			// the client can be told.
+			return
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			return
+		}
+	}
+	zw.Close()
+}
+
+// Delete schedules the caller's account for removal and clears their
+// cookies.
+func (h *AccountHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var req DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
+		return
+	}
+	purgeAfter, err := h.accounts.Delete(r.Context(), userID, req.Password)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	h.auditLog.Record(r, audit.Entry{
+		ActorID:    userID,
+		Action:     audit.ActionUserSelfDelete,
+		TargetType: "user",
+		TargetID:   userID,
+	})
+
+	http.SetCookie(w, newCookie(h.cookie, "access_token", "", -1))
+	http.SetCookie(w, newCookie(h.cookie, "refresh_token", "", -1))
+	http.SetCookie(w, newCookie(h.cookie, middleware.CSRFCookie, "", -1))
+	render.JSON(w, http.StatusAccepted, AccountDeletedResponse{
+		Message:    "Account deleted. It will be permanently removed after the grace period.",
+		PurgeAfter: purgeAfter,
+	})
+}
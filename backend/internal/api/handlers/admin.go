@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/api/dto"
+	"github.com/ISKOnnect/iskonnect-web/internal/api/render"
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+	"github.com/ISKOnnect/iskonnect-web/internal/audit"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+	"github.com/ISKOnnect/iskonnect-web/internal/service"
+)
+
+// AdminHandler serves the admin-only user and material management routes.
+// Every change is recorded in the audit log.
+type AdminHandler struct {
+	users     *service.UserService
+	materials *service.MaterialService
+	auditLog  *audit.Logger
+}
+
+func NewAdminHandler(users *service.UserService, materials *service.MaterialService, auditLog *audit.Logger) *AdminHandler {
+	return &AdminHandler{users: users, materials: materials, auditLog: auditLog}
+}
+
+type ModeratorRequest struct {
+	IsModerator bool `json:"is_moderator"`
+}
+
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	limit, offset := listPage(r)
+	users, total, err := h.users.List(r.Context(), limit, offset)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	renderList(w, r, dto.NewAdminUsers(users), total, limit, offset)
+}
+
+func (h *AdminHandler) ListDeletedUsers(w http.ResponseWriter, r *http.Request) {
+	limit, offset := listPage(r)
+	users, total, err := h.users.ListDeleted(r.Context(), limit, offset)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	renderList(w, r, dto.NewAdminUsers(users), total, limit, offset)
+}
+
+func (h *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	before, err := h.users.Delete(r.Context(), id)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	h.auditLog.Record(r, audit.Entry{Action: audit.ActionUserDelete, TargetType: "user", TargetID: id, Before: before})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.users.Restore(r.Context(), id); err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	h.auditLog.Record(r, audit.Entry{Action: audit.ActionUserRestore, TargetType: "user", TargetID: id, Before: map[string]bool{"deleted": true}, After: map[string]bool{"deleted": false}})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) SetModerator(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var req ModeratorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
+		return
+	}
+	was, err := h.users.SetModerator(r.Context(), id, req.IsModerator)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	h.auditLog.Record(r, audit.Entry{Action: audit.ActionUserSetModerator, TargetType: "user", TargetID: id, Before: map[string]bool{"is_moderator": was}, After: map[string]bool{"is_moderator": req.IsModerator}})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) ListMaterials(w http.ResponseWriter, r *http.Request) {
+	limit, offset := listPage(r)
+	materials, total, err := h.materials.ListAll(r.Context(), limit, offset)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	renderList(w, r, materials, total, limit, offset)
+}
+
+func (h *AdminHandler) ListDeletedMaterials(w http.ResponseWriter, r *http.Request) {
+	limit, offset := listPage(r)
+	materials, total, err := h.materials.ListDeleted(r.Context(), limit, offset)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	renderList(w, r, materials, total, limit, offset)
+}
+
+func (h *AdminHandler) UpdateMaterial(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var material models.Material
+	if err := json.NewDecoder(r.Body).Decode(&material); err != nil {
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
+		return
+	}
+	before, after, err := h.materials.Update(r.Context(), id, &material)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	h.auditLog.Record(r, audit.Entry{Action: audit.ActionMaterialUpdate, TargetType: "material", TargetID: id, Before: before, After: after})
+	render.JSON(w, http.StatusOK, after)
+}
+
+func (h *AdminHandler) DeleteMaterial(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	before, err := h.materials.Delete(r.Context(), id)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	h.auditLog.Record(r, audit.Entry{Action: audit.ActionMaterialDelete, TargetType: "material", TargetID: id, Before: before})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) RestoreMaterial(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.materials.Restore(r.Context(), id); err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	h.auditLog.Record(r, audit.Entry{Action: audit.ActionMaterialRestore, TargetType: "material", TargetID: id, Before: map[string]bool{"deleted": true}, After: map[string]bool{"deleted": false}})
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/api/render"
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+type AuditHandler struct {
+	auditModel models.AuditRepository
+	logger     *slog.Logger
+}
+
+func NewAuditHandler(events models.AuditRepository, logger *slog.Logger) *AuditHandler {
+	return &AuditHandler{auditModel: events, logger: logger}
+}
+
+// parseAuditFilter reads actor_id, action, target_type, target_id, since and
+// until (RFC 3339) from the query string.
+func parseAuditFilter(r *http.Request) (models.AuditFilter, bool) {
+	q := r.URL.Query()
+	filter := models.AuditFilter{
+		Action:     q.Get("action"),
+		TargetType: q.Get("target_type"),
+	}
+	for key, dst := range map[string]**int{"actor_id": &filter.ActorID, "target_id": &filter.TargetID} {
+		if v := q.Get(key); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				return filter, false
+			}
+			*dst = &id
+		}
+	}
+	for key, dst := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := q.Get(key); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, false
+			}
+			*dst = &t
+		}
+	}
+	return filter, true
+}
+
+func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	filter, ok := parseAuditFilter(r)
+	if !ok {
+		render.Error(w, r, apierror.BadRequest("Invalid filter"))
+		return
+	}
+	filter.Limit, filter.Offset = parsePagination(r)
+
+	events, total, err := h.auditModel.List(r.Context(), filter)
+	if err != nil {
+		render.Error(w, r, apierror.Internal("Failed to list audit events", err))
+		return
+	}
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"events": events,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
+
+// Export streams every matching event as CSV or JSON Lines (format=csv or
+// format=jsonl) for incident reviews.
+func (h *AuditHandler) Export(w http.ResponseWriter, r *http.Request) {
+	filter, ok := parseAuditFilter(r)
+	if !ok {
+		render.Error(w, r, apierror.BadRequest("Invalid filter"))
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "jsonl"
+	}
+	filename := "audit-" + time.Now().UTC().Format("20060102T150405Z")
+
+	var write func(*models.AuditEvent) error
+	var flush func() error
+	switch format {
+	case "jsonl":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.jsonl"`)
+		enc := json.NewEncoder(w)
+		write = func(e *models.AuditEvent) error { return enc.Encode(e) }
+		flush = func() error { return nil }
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"id", "created_at", "actor_id", "action", "target_type", "target_id", "request_id", "ip", "diff"})
+		write = func(e *models.AuditEvent) error {
+			return cw.Write([]string{
+				strconv.FormatInt(e.ID, 10),
+				e.CreatedAt.UTC().Format(time.RFC3339),
+				optionalInt(e.ActorID),
+				e.Action,
+				e.TargetType,
+				optionalInt(e.TargetID),
+				e.RequestID,
+				e.IP,
+				string(e.Diff),
+			})
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	default:
+		render.Error(w, r, apierror.Invalid("format", "Invalid format (csv, jsonl)"))
+		return
+	}
+
+	// Headers are already sent once rows start streaming, so failures past
+	// this point can only be logged.
+	if err := h.auditModel.Each(r.Context(), filter, write); err != nil {
+		h.logger.ErrorContext(r.Context(), "audit export failed", "error", err)
+	}
+	if err := flush(); err != nil {
+		h.logger.ErrorContext(r.Context(), "audit export flush failed", "error", err)
+	}
+}
+
+func optionalInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
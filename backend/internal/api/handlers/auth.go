@@ -3,31 +3,43 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/ISKOnnect/iskonnect-web/internal/api/middleware"
+	"github.com/ISKOnnect/iskonnect-web/internal/api/render"
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+	"github.com/ISKOnnect/iskonnect-web/internal/audit"
 	"github.com/ISKOnnect/iskonnect-web/internal/config"
 	"github.com/ISKOnnect/iskonnect-web/internal/email"
+	"github.com/ISKOnnect/iskonnect-web/internal/metrics"
 	"github.com/ISKOnnect/iskonnect-web/internal/models"
 	"github.com/ISKOnnect/iskonnect-web/internal/utils"
 )
 
 type AuthHandler struct {
-	db          *sql.DB
 	cfg         *config.Config
-	userModel   *models.UserModel
+	keys        *utils.Keyring
+	userModel   models.UserRepository
 	emailSender *email.Sender
+	auditLog    *audit.Logger
+	logger      *slog.Logger
+	metrics     *metrics.Metrics
 }
 
-func NewAuthHandler(db *sql.DB, cfg *config.Config) *AuthHandler {
+func NewAuthHandler(cfg *config.Config, keys *utils.Keyring, users models.UserRepository, emailSender *email.Sender, auditLog *audit.Logger, logger *slog.Logger, m *metrics.Metrics) *AuthHandler {
 	return &AuthHandler{
-		db:          db,
 		cfg:         cfg,
-		userModel:   models.NewUserModel(db),
-		emailSender: email.NewSender(cfg.Email),
+		keys:        keys,
+		userModel:   users,
+		emailSender: emailSender,
+		auditLog:    auditLog,
+		logger:      logger,
+		metrics:     m,
 	}
 }
 
@@ -45,6 +57,31 @@ type LoginRequest struct {
 	Password      string `json:"password"`
 }
 
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type VerifyOTPRequest struct {
+	Email string `json:"email"`
+	OTP   string `json:"otp"`
+}
+
+type ResetPasswordRequest struct {
+	Email       string `json:"email"`
+	ResetToken  string `json:"reset_token"`
+	NewPassword string `json:"new_password"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email"`
+	Password string `json:"password"`
+}
+
 func isValidStudentNumber(sn string) bool {
 	return regexp.MustCompile(`^\d{4}-\d{5}-[A-Z]{2}-\d$`).MatchString(sn)
 }
@@ -68,68 +105,61 @@ func isValidName(name string) bool {
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
 		return
 	}
 
 	if !isValidStudentNumber(req.StudentNumber) {
-		http.Error(w, "Invalid student number format (e.g., 2023-00239-MN-0)", http.StatusBadRequest)
+		render.Error(w, r, apierror.Invalid("student_number", "Invalid student number format (e.g., 2023-00239-MN-0)"))
 		return
 	}
-	if !isValidName(req.FirstName) || !isValidName(req.LastName) {
-		http.Error(w, "Names must be 2-50 letters", http.StatusBadRequest)
+	var nameErrors []apierror.FieldError
+	if !isValidName(req.FirstName) {
+		nameErrors = append(nameErrors, apierror.FieldError{Field: "first_name", Message: "Must be 2-50 letters"})
+	}
+	if !isValidName(req.LastName) {
+		nameErrors = append(nameErrors, apierror.FieldError{Field: "last_name", Message: "Must be 2-50 letters"})
+	}
+	if len(nameErrors) > 0 {
+		render.Error(w, r, apierror.Validation("Names must be 2-50 letters", nameErrors...))
 		return
 	}
 	if !isValidEmail(req.Email) {
-		http.Error(w, "Invalid email", http.StatusBadRequest)
+		render.Error(w, r, apierror.Invalid("email", "Invalid email"))
 		return
 	}
 	if !isValidPassword(req.Password) {
-		http.Error(w, "Password must be 8+ chars with uppercase, lowercase, number, and special char", http.StatusBadRequest)
+		render.Error(w, r, apierror.Invalid("password", "Password must be 8+ chars with uppercase, lowercase, number, and special char"))
 		return
 	}
 	if req.Password != req.ConfirmPassword {
-		http.Error(w, "Passwords do not match", http.StatusBadRequest)
+		render.Error(w, r, apierror.Invalid("confirm_password", "Passwords do not match"))
 		return
 	}
 
-	if _, err := h.userModel.GetByEmail(req.Email); err == nil {
-		http.Error(w, "Email already registered", http.StatusConflict)
+	if _, err := h.userModel.GetByEmail(r.Context(), req.Email); err == nil {
+		render.Error(w, r, apierror.Conflict("Email already registered"))
 		return
 	}
-	if _, err := h.userModel.GetByStudentNumber(req.StudentNumber); err == nil {
-		http.Error(w, "Student number already registered", http.StatusConflict)
+	if _, err := h.userModel.GetByStudentNumber(r.Context(), req.StudentNumber); err == nil {
+		render.Error(w, r, apierror.Conflict("Student number already registered"))
 		return
 	}
 
-	hashedPassword, err := utils.HashPassword(req.Password)
+	hashedPassword, err := utils.HashPassword(r.Context(), req.Password)
 	if err != nil {
-		log.Printf("Hash failed: %v", err)
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "password hash failed", "error", err)
+		render.Error(w, r, apierror.Internal("Internal error", err))
 		return
 	}
 
-	tx, err := h.db.Begin()
-	if err != nil {
-		http.Error(w, "Internal error", http.StatusInternalServerError)
-		return
-	}
-	defer tx.Rollback()
-
-	var userID int
-	err = tx.QueryRow(`
-		INSERT INTO user_credentials (email, password_hash, created_at)
-		VALUES ($1, $2, $3) RETURNING id`,
-		strings.ToLower(req.Email), hashedPassword, time.Now(),
-	).Scan(&userID)
+	token, err := utils.GenerateRandomToken(32)
 	if err != nil {
-		log.Printf("Credential insert failed: %v", err)
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+		render.Error(w, r, apierror.Internal("Internal error", err))
 		return
 	}
 
 	user := &models.User{
-		ID:            userID,
 		StudentNumber: req.StudentNumber,
 		FirstName:     strings.TrimSpace(req.FirstName),
 		LastName:      strings.TrimSpace(req.LastName),
@@ -140,304 +170,473 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		CreatedAt:     time.Now(),
 		UpdatedAt:     time.Now(),
 	}
-	if err := h.userModel.Create(tx, user); err != nil {
-		log.Printf("User insert failed: %v", err)
-		http.Error(w, "Internal error", http.StatusInternalServerError)
-		return
-	}
-
-	token, err := utils.GenerateRandomToken(32)
-	if err != nil {
-		http.Error(w, "Internal error", http.StatusInternalServerError)
-		return
-	}
-	if err := h.userModel.StoreVerificationToken(tx, userID, token, time.Now().Add(24*time.Hour)); err != nil {
-		http.Error(w, "Internal error", http.StatusInternalServerError)
-		return
-	}
-
-	if err := tx.Commit(); err != nil {
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+	if err := h.userModel.Register(r.Context(), user, hashedPassword, utils.HashToken(token), time.Now().Add(24*time.Hour)); err != nil {
+		h.logger.ErrorContext(r.Context(), "user insert failed", "error", err)
+		render.Error(w, r, apierror.Internal("Internal error", err))
 		return
 	}
+	h.metrics.Registered()
 
-	if err := h.emailSender.SendVerificationEmail(req.Email, token); err != nil {
-		log.Printf("Email send failed: %v", err)
+	if err := h.emailSender.SendVerificationEmail(r.Context(), req.Email, token); err != nil {
+		h.logger.ErrorContext(r.Context(), "verification email failed", "error", err)
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Registered. Verify your email."})
+	render.JSON(w, http.StatusCreated, map[string]string{"message": "Registered. Verify your email."})
 }
 
 func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
 	token := r.URL.Query().Get("token")
 	if token == "" {
-		http.Error(w, "Missing token", http.StatusBadRequest)
+		render.Error(w, r, apierror.BadRequest("Missing token"))
 		return
 	}
 
-	userID, err := h.userModel.VerifyEmailToken(token)
-	if err != nil {
-		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+	userID, err := h.userModel.VerifyEmail(r.Context(), utils.HashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		render.Error(w, r, apierror.BadRequest("Invalid or expired token"))
 		return
 	}
-
-	if err := h.userModel.VerifyEmail(userID); err != nil {
-		http.Error(w, "Verification failed", http.StatusInternalServerError)
+	if err != nil {
+		render.Error(w, r, apierror.Internal("Verification failed", err))
 		return
 	}
+	h.auditLog.Record(r, audit.Entry{
+		ActorID:    userID,
+		Action:     audit.ActionEmailVerified,
+		TargetType: "user",
+		TargetID:   userID,
+		Before:     map[string]bool{"email_verified": false},
+		After:      map[string]bool{"email_verified": true},
+	})
 
-	if err := h.userModel.DeleteVerificationToken(token); err != nil {
-		log.Printf("Token delete failed: %v", err)
-	}
-
-	json.NewEncoder(w).Encode(map[string]string{"message": "Email verified"})
+	render.JSON(w, http.StatusOK, map[string]string{"message": "Email verified"})
 }
 
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
 		return
 	}
 
 	if !isValidStudentNumber(req.StudentNumber) {
-		http.Error(w, "Invalid student number", http.StatusBadRequest)
+		render.Error(w, r, apierror.Invalid("student_number", "Invalid student number"))
 		return
 	}
 	if req.Password == "" {
-		http.Error(w, "Password required", http.StatusBadRequest)
+		render.Error(w, r, apierror.Invalid("password", "Password required"))
 		return
 	}
 
-	user, err := h.userModel.GetByStudentNumber(req.StudentNumber)
+	user, err := h.userModel.GetByStudentNumber(r.Context(), req.StudentNumber)
 	if err != nil {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		h.metrics.LoginAttempted(false)
+		render.Error(w, r, apierror.Unauthorized("Invalid credentials"))
 		return
 	}
 
 	if !user.EmailVerified {
-		http.Error(w, "Email not verified", http.StatusUnauthorized)
+		h.metrics.LoginAttempted(false)
+		render.Error(w, r, apierror.Unauthorized("Email not verified"))
 		return
 	}
 
-	hash, err := h.userModel.GetPasswordHash(user.ID)
+	hash, err := h.userModel.GetPasswordHash(r.Context(), user.ID)
 	if err != nil || utils.CheckPassword(hash, req.Password) != nil {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		h.metrics.LoginAttempted(false)
+		render.Error(w, r, apierror.Unauthorized("Invalid credentials"))
 		return
 	}
 
-	accessToken, err := utils.GenerateJWT(user, h.cfg.JWT.Secret, 24)
-	if err != nil {
-		http.Error(w, "Internal error", http.StatusInternalServerError)
-		return
-	}
-	refreshToken, err := utils.GenerateJWT(user, h.cfg.JWT.Secret, 168)
+	s, err := h.startSession(w, user)
 	if err != nil {
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+		render.Error(w, r, apierror.Internal("Internal error", err))
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "access_token",
-		Value:    accessToken,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   h.cfg.Server.Environment == "production",
-		MaxAge:   24 * 3600,
-		SameSite: http.SameSiteStrictMode,
-	})
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    refreshToken,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   h.cfg.Server.Environment == "production",
-		MaxAge:   168 * 3600,
-		SameSite: http.SameSiteStrictMode,
+	h.metrics.LoginAttempted(true)
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"user":          embeddedUser(r, user),
+		"access_token":  s.access,
+		"refresh_token": s.refresh,
+		"csrf_token":    s.csrf,
 	})
+}
 
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"user":          user,
-		"access_token":  accessToken,
-		"refresh_token": refreshToken,
-	})
+type session struct {
+	access, refresh, csrf string
+}
+
+// startSession issues access, refresh and CSRF tokens for user and sets
+// them as cookies.
+func (h *AuthHandler) startSession(w http.ResponseWriter, user *models.User) (*session, error) {
+	accessToken, err := h.keys.GenerateJWT(user, 24)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := h.keys.GenerateJWT(user, 168)
+	if err != nil {
+		return nil, err
+	}
+	csrfCookie, err := newCSRFCookie(h.cfg.Cookie, 168*3600)
+	if err != nil {
+		return nil, err
+	}
+
+	http.SetCookie(w, newCookie(h.cfg.Cookie, "access_token", accessToken, 24*3600))
+	http.SetCookie(w, newCookie(h.cfg.Cookie, "refresh_token", refreshToken, 168*3600))
+	http.SetCookie(w, csrfCookie)
+	return &session{access: accessToken, refresh: refreshToken, csrf: csrfCookie.Value}, nil
 }
 
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     "access_token",
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		MaxAge:   -1,
-	})
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		MaxAge:   -1,
-	})
-	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out"})
+	http.SetCookie(w, newCookie(h.cfg.Cookie, "access_token", "", -1))
+	http.SetCookie(w, newCookie(h.cfg.Cookie, "refresh_token", "", -1))
+	http.SetCookie(w, newCookie(h.cfg.Cookie, middleware.CSRFCookie, "", -1))
+	render.JSON(w, http.StatusOK, map[string]string{"message": "Logged out"})
 }
 
 func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	cookie, err := r.Cookie("refresh_token")
 	if err != nil {
-		http.Error(w, "No refresh token", http.StatusUnauthorized)
+		render.Error(w, r, apierror.Unauthorized("No refresh token"))
 		return
 	}
 
-	claims, err := utils.ValidateJWT(cookie.Value, h.cfg.JWT.Secret)
+	claims, err := h.keys.ValidateJWT(cookie.Value)
 	if err != nil {
-		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		render.Error(w, r, apierror.Unauthorized("Invalid refresh token"))
 		return
 	}
 
-	user, err := h.userModel.GetByID(claims.UserID)
+	user, err := h.userModel.GetByID(r.Context(), claims.UserID)
 	if err != nil {
-		http.Error(w, "User not found", http.StatusUnauthorized)
+		render.Error(w, r, apierror.Unauthorized("User not found"))
+		return
+	}
+	if user.SessionVersion != claims.SessionVersion {
+		render.Error(w, r, apierror.Unauthorized("Session revoked"))
 		return
 	}
 
-	accessToken, err := utils.GenerateJWT(user, h.cfg.JWT.Secret, 24)
+	accessToken, err := h.keys.GenerateJWT(user, 24)
 	if err != nil {
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+		render.Error(w, r, apierror.Internal("Internal error", err))
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "access_token",
-		Value:    accessToken,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   h.cfg.Server.Environment == "production",
-		MaxAge:   24 * 3600,
-		SameSite: http.SameSiteStrictMode,
+	csrfCookie, err := newCSRFCookie(h.cfg.Cookie, 168*3600)
+	if err != nil {
+		render.Error(w, r, apierror.Internal("Internal error", err))
+		return
+	}
+
+	http.SetCookie(w, newCookie(h.cfg.Cookie, "access_token", accessToken, 24*3600))
+	http.SetCookie(w, csrfCookie)
+	render.JSON(w, http.StatusOK, map[string]string{
+		"access_token": accessToken,
+		"csrf_token":   csrfCookie.Value,
 	})
-	json.NewEncoder(w).Encode(map[string]string{"access_token": accessToken})
 }
 
 func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Email string `json:"email"`
-	}
+	var req ForgotPasswordRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
 		return
 	}
 
 	if !isValidEmail(req.Email) {
-		http.Error(w, "Invalid email", http.StatusBadRequest)
+		render.Error(w, r, apierror.Invalid("email", "Invalid email"))
 		return
 	}
 
-	user, err := h.userModel.GetByEmail(req.Email)
+	user, err := h.userModel.GetByEmail(r.Context(), req.Email)
 	if err != nil {
-		json.NewEncoder(w).Encode(map[string]string{"message": "If email exists, reset OTP sent"})
+		render.JSON(w, http.StatusOK, map[string]string{"message": "If email exists, reset OTP sent"})
 		return
 	}
 
 	otp, err := utils.GenerateOTP(6)
 	if err != nil {
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+		render.Error(w, r, apierror.Internal("Internal error", err))
 		return
 	}
 
-	if err := h.userModel.StoreOTP(user.ID, otp, time.Now().Add(15*time.Minute)); err != nil {
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+	// A user out of OTP guesses gets no new OTP, but the response must not
+	// tell whether the email is registered.
+	err = h.userModel.StoreOTP(r.Context(), user.ID, utils.HashToken(otp), time.Now().Add(15*time.Minute))
+	if errors.Is(err, models.ErrTooManyAttempts) {
+		render.JSON(w, http.StatusOK, map[string]string{"message": "If email exists, reset OTP sent"})
 		return
 	}
+	if err != nil {
+		render.Error(w, r, apierror.Internal("Internal error", err))
+		return
+	}
+	h.auditLog.Record(r, audit.Entry{
+		ActorID:    user.ID,
+		Action:     audit.ActionPasswordResetStart,
+		TargetType: "user",
+		TargetID:   user.ID,
+	})
 
-	if err := h.emailSender.SendPasswordResetEmail(req.Email, otp); err != nil {
-		log.Printf("Email send failed: %v", err)
+	if err := h.emailSender.SendPasswordResetEmail(r.Context(), req.Email, otp); err != nil {
+		h.logger.ErrorContext(r.Context(), "password reset email failed", "error", err)
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{"message": "If email exists, reset OTP sent"})
+	render.JSON(w, http.StatusOK, map[string]string{"message": "If email exists, reset OTP sent"})
 }
 
 func (h *AuthHandler) VerifyOTP(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Email string `json:"email"`
-		OTP   string `json:"otp"`
-	}
+	var req VerifyOTPRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
 		return
 	}
 
 	if !isValidEmail(req.Email) || len(req.OTP) != 6 || !regexp.MustCompile(`^\d{6}$`).MatchString(req.OTP) {
-		http.Error(w, "Invalid email or OTP", http.StatusBadRequest)
+		render.Error(w, r, apierror.Validation("Invalid email or OTP"))
 		return
 	}
 
-	user, err := h.userModel.GetByEmail(req.Email)
+	user, err := h.userModel.GetByEmail(r.Context(), req.Email)
 	if err != nil {
-		http.Error(w, "Invalid OTP", http.StatusBadRequest)
-		return
-	}
-
-	if err := h.userModel.VerifyOTP(user.ID, req.OTP); err != nil {
-		http.Error(w, "Invalid or expired OTP", http.StatusBadRequest)
+		render.Error(w, r, apierror.Invalid("otp", "Invalid OTP"))
 		return
 	}
 
 	resetToken, err := utils.GenerateRandomToken(32)
 	if err != nil {
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+		render.Error(w, r, apierror.Internal("Internal error", err))
 		return
 	}
 
-	if err := h.userModel.StoreResetToken(user.ID, resetToken, time.Now().Add(15*time.Minute)); err != nil {
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+	err = h.userModel.ExchangeOTP(r.Context(), user.ID, utils.HashToken(req.OTP), utils.HashToken(resetToken), time.Now().Add(15*time.Minute))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		render.Error(w, r, apierror.BadRequest("Invalid or expired OTP"))
+		return
+	case errors.Is(err, models.ErrTooManyAttempts):
+		render.Error(w, r, apierror.RateLimited("Too many wrong attempts. Request a new OTP later."))
+		return
+	case err != nil:
+		render.Error(w, r, apierror.Internal("Internal error", err))
 		return
 	}
+	h.auditLog.Record(r, audit.Entry{
+		ActorID:    user.ID,
+		Action:     audit.ActionPasswordResetOTP,
+		TargetType: "user",
+		TargetID:   user.ID,
+	})
 
-	json.NewEncoder(w).Encode(map[string]string{"reset_token": resetToken})
+	render.JSON(w, http.StatusOK, map[string]string{"reset_token": resetToken})
 }
 
 func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Email       string `json:"email"`
-		ResetToken  string `json:"reset_token"`
-		NewPassword string `json:"new_password"`
-	}
+	var req ResetPasswordRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
 		return
 	}
 
 	if !isValidEmail(req.Email) || req.ResetToken == "" || !isValidPassword(req.NewPassword) {
-		http.Error(w, "Invalid email, token, or password", http.StatusBadRequest)
+		render.Error(w, r, apierror.Validation("Invalid email, token, or password"))
+		return
+	}
+
+	user, err := h.userModel.GetByEmail(r.Context(), req.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		render.Error(w, r, apierror.NotFound("User not found"))
+		return
+	}
+	if err != nil {
+		render.Error(w, r, apierror.Internal("Failed to get user", err))
 		return
 	}
 
-	user, err := h.userModel.GetByEmail(req.Email)
+	hash, err := utils.HashPassword(r.Context(), req.NewPassword)
+	if err != nil {
+		render.Error(w, r, apierror.Internal("Internal error", err))
+		return
+	}
+
+	err = h.userModel.ResetPassword(r.Context(), user.ID, utils.HashToken(req.ResetToken), hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		render.Error(w, r, apierror.BadRequest("Invalid or expired token"))
+		return
+	}
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		render.Error(w, r, apierror.Internal("Update failed", err))
 		return
 	}
+	h.auditLog.Record(r, audit.Entry{
+		ActorID:    user.ID,
+		Action:     audit.ActionPasswordReset,
+		TargetType: "user",
+		TargetID:   user.ID,
+		After:      map[string]bool{"password_changed": true},
+	})
 
-	if err := h.userModel.VerifyResetToken(user.ID, req.ResetToken); err != nil {
-		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+	render.JSON(w, http.StatusOK, map[string]string{"message": "Password reset successful. Sign in again on every device."})
+}
+
+// ChangePassword sets a new password after checking the current one. Every
+// other session and every personal access token is revoked; the caller gets
+// fresh tokens and cookies.
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
+		return
+	}
+
+	if !isValidPassword(req.NewPassword) {
+		render.Error(w, r, apierror.Invalid("new_password", "Password must be 8+ chars with uppercase, lowercase, number, and special char"))
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		render.Error(w, r, apierror.Invalid("new_password", "New password must differ from the current one"))
+		return
+	}
+	if !h.checkPassword(w, r, userID, req.CurrentPassword, "current_password") {
 		return
 	}
 
-	hash, err := utils.HashPassword(req.NewPassword)
+	user, err := h.userModel.GetByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		render.Error(w, r, apierror.NotFound("User not found"))
+		return
+	}
+	if err != nil {
+		render.Error(w, r, apierror.Internal("Failed to get user", err))
+		return
+	}
+	hash, err := utils.HashPassword(r.Context(), req.NewPassword)
+	if err != nil {
+		render.Error(w, r, apierror.Internal("Internal error", err))
+		return
+	}
+	user.SessionVersion, err = h.userModel.ChangePassword(r.Context(), userID, hash)
 	if err != nil {
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+		render.Error(w, r, apierror.Internal("Update failed", err))
 		return
 	}
+	h.auditLog.Record(r, audit.Entry{
+		ActorID:    userID,
+		Action:     audit.ActionPasswordChange,
+		TargetType: "user",
+		TargetID:   userID,
+		After:      map[string]bool{"password_changed": true},
+	})
 
-	if err := h.userModel.UpdatePassword(user.ID, hash); err != nil {
-		http.Error(w, "Update failed", http.StatusInternalServerError)
+	s, err := h.startSession(w, user)
+	if err != nil {
+		render.Error(w, r, apierror.Internal("Internal error", err))
 		return
 	}
+	render.JSON(w, http.StatusOK, map[string]string{
+		"message":       "Password changed. Other sessions have been signed out.",
+		"access_token":  s.access,
+		"refresh_token": s.refresh,
+		"csrf_token":    s.csrf,
+	})
+}
 
-	if err := h.userModel.DeleteResetToken(user.ID, req.ResetToken); err != nil {
-		log.Printf("Token delete failed: %v", err)
+// ChangeEmail mails a confirmation link to the new address. The address on
+// the account only changes once ConfirmEmail is called with that link.
+func (h *AuthHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var req ChangeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
+		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{"message": "Password reset successful"})
-}
\ No newline at end of file
+	newEmail := strings.ToLower(strings.TrimSpace(req.NewEmail))
+	if !isValidEmail(newEmail) {
+		render.Error(w, r, apierror.Invalid("new_email", "Invalid email"))
+		return
+	}
+	if !h.checkPassword(w, r, userID, req.Password, "password") {
+		return
+	}
+	if existing, err := h.userModel.GetByEmail(r.Context(), newEmail); err == nil {
+		if existing.ID == userID {
+			render.Error(w, r, apierror.Invalid("new_email", "This is already your email"))
+		} else {
+			render.Error(w, r, apierror.Conflict("Email already registered"))
+		}
+		return
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		render.Error(w, r, apierror.Internal("Internal error", err))
+		return
+	}
+	if err := h.userModel.RequestEmailChange(r.Context(), userID, newEmail, utils.HashToken(token), time.Now().Add(24*time.Hour)); err != nil {
+		render.Error(w, r, apierror.Internal("Internal error", err))
+		return
+	}
+	h.auditLog.Record(r, audit.Entry{
+		ActorID:    userID,
+		Action:     audit.ActionEmailChangeStart,
+		TargetType: "user",
+		TargetID:   userID,
+	})
+
+	if err := h.emailSender.SendEmailChangeEmail(r.Context(), newEmail, token); err != nil {
+		h.logger.ErrorContext(r.Context(), "email change confirmation failed", "error", err)
+	}
+
+	render.JSON(w, http.StatusAccepted, map[string]string{"message": "Check your new email for a confirmation link"})
+}
+
+func (h *AuthHandler) ConfirmEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		render.Error(w, r, apierror.BadRequest("Missing token"))
+		return
+	}
+
+	userID, _, _, err := h.userModel.ConfirmEmailChange(r.Context(), utils.HashToken(token))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		render.Error(w, r, apierror.BadRequest("Invalid or expired token"))
+		return
+	case errors.Is(err, models.ErrEmailTaken):
+		render.Error(w, r, apierror.Conflict("Email already registered"))
+		return
+	case err != nil:
+		render.Error(w, r, apierror.Internal("Email change failed", err))
+		return
+	}
+	h.auditLog.Record(r, audit.Entry{
+		ActorID:    userID,
+		Action:     audit.ActionEmailChanged,
+		TargetType: "user",
+		TargetID:   userID,
+		Before:     map[string]bool{"email_changed": false},
+		After:      map[string]bool{"email_changed": true},
+	})
+
+	render.JSON(w, http.StatusOK, map[string]string{"message": "Email changed"})
+}
+
+// checkPassword verifies the user's current password, rendering a
+// validation error on field if it doesn't match.
+func (h *AuthHandler) checkPassword(w http.ResponseWriter, r *http.Request, userID int, password, field string) bool {
+	hash, err := h.userModel.GetPasswordHash(r.Context(), userID)
+	if err != nil {
+		render.Error(w, r, apierror.Internal("Internal error", err))
+		return false
+	}
+	if utils.CheckPassword(hash, password) != nil {
+		render.Error(w, r, apierror.Invalid(field, "Incorrect password"))
+		return false
+	}
+	return true
+}
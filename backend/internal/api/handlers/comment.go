@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/api/render"
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+	"github.com/ISKOnnect/iskonnect-web/internal/email"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+	"github.com/ISKOnnect/iskonnect-web/internal/service"
+)
+
+// CommentHandler serves the comment threads of materials and sends the
+// notification emails the comment service asks for.
+type CommentHandler struct {
+	comments    *service.CommentService
+	emailSender *email.Sender
+	outbox      *email.Outbox
+	logger      *slog.Logger
+}
+
+func NewCommentHandler(comments *service.CommentService, emailSender *email.Sender, outbox *email.Outbox, logger *slog.Logger) *CommentHandler {
+	return &CommentHandler{
+		comments:    comments,
+		emailSender: emailSender,
+		outbox:      outbox,
+		logger:      logger,
+	}
+}
+
+type CommentRequest struct {
+	Body     string `json:"body"`
+	ParentID *int   `json:"parent_id"`
+}
+
+func parsePagination(r *http.Request) (limit, offset int) {
+	limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset, _ = strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// commentIDs reads the {id} material and {commentID} URL parameters.
+func commentIDs(w http.ResponseWriter, r *http.Request) (materialID, commentID int, ok bool) {
+	if materialID, ok = pathID(w, r, "id"); !ok {
+		return 0, 0, false
+	}
+	if commentID, ok = pathID(w, r, "commentID"); !ok {
+		return 0, 0, false
+	}
+	return materialID, commentID, true
+}
+
+func (h *CommentHandler) List(w http.ResponseWriter, r *http.Request) {
+	materialID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	limit, offset := parsePagination(r)
+	comments, total, err := h.comments.List(r.Context(), materialID, limit, offset)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"comments": comments,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+func (h *CommentHandler) ListReplies(w http.ResponseWriter, r *http.Request) {
+	materialID, parentID, ok := commentIDs(w, r)
+	if !ok {
+		return
+	}
+	limit, offset := parsePagination(r)
+	replies, total, err := h.comments.ListReplies(r.Context(), materialID, parentID, limit, offset)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"comments": replies,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+func (h *CommentHandler) Create(w http.ResponseWriter, r *http.Request) {
+	materialID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	userID := r.Context().Value("user_id").(int)
+
+	var req CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
+		return
+	}
+	created, notices, err := h.comments.Create(r.Context(), materialID, userID, req.Body, req.ParentID)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	h.notify(r, created, notices)
+	render.JSON(w, http.StatusCreated, created)
+}
+
+func (h *CommentHandler) Update(w http.ResponseWriter, r *http.Request) {
+	materialID, commentID, ok := commentIDs(w, r)
+	if !ok {
+		return
+	}
+	userID := r.Context().Value("user_id").(int)
+
+	var req CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
+		return
+	}
+	updated, notices, err := h.comments.Update(r.Context(), materialID, commentID, userID, req.Body)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	h.notify(r, updated, notices)
+	render.JSON(w, http.StatusOK, updated)
+}
+
+func (h *CommentHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	materialID, commentID, ok := commentIDs(w, r)
+	if !ok {
+		return
+	}
+	userID := r.Context().Value("user_id").(int)
+	if err := h.comments.Delete(r.Context(), materialID, commentID, userID); err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CommentHandler) History(w http.ResponseWriter, r *http.Request) {
+	materialID, commentID, ok := commentIDs(w, r)
+	if !ok {
+		return
+	}
+	edits, err := h.comments.History(r.Context(), materialID, commentID)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	render.JSON(w, http.StatusOK, edits)
+}
+
+// notify sends notices through the outbox. The sends outlive the request,
+// so their context is not cancelled with it and failures are only logged.
+func (h *CommentHandler) notify(r *http.Request, comment *models.Comment, notices []service.CommentNotice) {
+	if len(notices) == 0 {
+		return
+	}
+	ctx := context.WithoutCancel(r.Context())
+	h.outbox.Go(func() {
+		for _, n := range notices {
+			if err := h.emailSender.SendCommentNotification(ctx, n.To, n.Heading, n.MaterialTitle, n.Author, n.Body); err != nil {
+				h.logger.ErrorContext(ctx, "comment notification failed", "comment_id", comment.ID, "error", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/api/middleware"
+	"github.com/ISKOnnect/iskonnect-web/internal/config"
+	"github.com/ISKOnnect/iskonnect-web/internal/utils"
+)
+
+// newCookie builds an HttpOnly cookie with the configured domain, SameSite
+// and Secure attributes. A negative maxAge deletes the cookie.
+func newCookie(cfg config.CookieConfig, name, value string, maxAge int) *http.Cookie {
+	sameSite := http.SameSiteStrictMode
+	switch cfg.SameSite {
+	case "lax":
+		sameSite = http.SameSiteLaxMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	}
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   cfg.Domain,
+		HttpOnly: true,
+		Secure:   cfg.Secure,
+		MaxAge:   maxAge,
+		SameSite: sameSite,
+	}
+}
+
+// newCSRFCookie issues a fresh double-submit token. Unlike the session
+// cookies it is readable from JavaScript so the client can echo it in the
+// X-CSRF-Token header.
+func newCSRFCookie(cfg config.CookieConfig, maxAge int) (*http.Cookie, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	cookie := newCookie(cfg, middleware.CSRFCookie, token, maxAge)
+	cookie.HttpOnly = false
+	return cookie, nil
+}
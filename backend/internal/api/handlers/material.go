@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/api/render"
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+	"github.com/ISKOnnect/iskonnect-web/internal/metrics"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+	"github.com/ISKOnnect/iskonnect-web/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type MaterialHandler struct {
+	materials *service.MaterialService
+	metrics   *metrics.Metrics
+}
+
+func NewMaterialHandler(materials *service.MaterialService, m *metrics.Metrics) *MaterialHandler {
+	return &MaterialHandler{materials: materials, metrics: m}
+}
+
+type VoteRequest struct {
+	VoteType string `json:"vote_type"`
+}
+
+// pathID parses the positive integer URL parameter name, rendering a 400 and
+// returning false if it is not one.
+func pathID(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, name))
+	if err != nil || id <= 0 {
+		render.Error(w, r, apierror.BadRequest("Invalid ID"))
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *MaterialHandler) List(w http.ResponseWriter, r *http.Request) {
+	limit, offset := listPage(r)
+	materials, total, err := h.materials.List(r.Context(), limit, offset)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	renderList(w, r, materials, total, limit, offset)
+}
+
+func (h *MaterialHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var material models.Material
+	if err := json.NewDecoder(r.Body).Decode(&material); err != nil {
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
+		return
+	}
+	user, err := h.materials.Upload(r.Context(), userID, &material)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	h.metrics.Uploaded()
+	render.JSON(w, http.StatusCreated, map[string]interface{}{
+		"material": material,
+		"user":     embeddedUser(r, user),
+	})
+}
+
+func (h *MaterialHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	material, err := h.materials.Get(r.Context(), id)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	render.JSON(w, http.StatusOK, material)
+}
+
+func (h *MaterialHandler) Vote(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	userID := r.Context().Value("user_id").(int)
+	var req VoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
+		return
+	}
+	material, err := h.materials.Vote(r.Context(), id, userID, req.VoteType)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	h.metrics.Voted(strings.ToUpper(req.VoteType))
+	render.JSON(w, http.StatusOK, material)
+}
+
+func (h *MaterialHandler) Bookmark(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	userID := r.Context().Value("user_id").(int)
+	if err := h.materials.Bookmark(r.Context(), id, userID); err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	render.JSON(w, http.StatusCreated, map[string]string{"message": "Bookmarked"})
+}
+
+func (h *MaterialHandler) Bookmarks(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	limit, offset := listPage(r)
+	bookmarks, total, err := h.materials.Bookmarks(r.Context(), userID, limit, offset)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	renderList(w, r, bookmarks, total, limit, offset)
+}
+
+func (h *MaterialHandler) Leaderboard(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	filter, entries, me, err := h.materials.Leaderboard(r.Context(), models.LeaderboardFilter{
+		Window:  q.Get("window"),
+		College: strings.TrimSpace(q.Get("college")),
+		Course:  strings.TrimSpace(q.Get("course")),
+		Limit:   limit,
+	}, userID)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"window":  filter.Window,
+		"college": filter.College,
+		"course":  filter.Course,
+		"entries": entries,
+		"me":      me,
+	})
+}
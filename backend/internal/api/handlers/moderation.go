@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/api/render"
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+	"github.com/ISKOnnect/iskonnect-web/internal/audit"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+	"github.com/ISKOnnect/iskonnect-web/internal/service"
+)
+
+type ModerationHandler struct {
+	reports  *service.ReportService
+	auditLog *audit.Logger
+}
+
+func NewModerationHandler(reports *service.ReportService, auditLog *audit.Logger) *ModerationHandler {
+	return &ModerationHandler{
+		reports:  reports,
+		auditLog: auditLog,
+	}
+}
+
+type ReportRequest struct {
+	Reason  string `json:"reason"`
+	Details string `json:"details"`
+}
+
+type ReportStatusRequest struct {
+	Status string `json:"status"`
+}
+
+type BulkActionRequest struct {
+	Action    string `json:"action"`
+	ReportIDs []int  `json:"report_ids"`
+}
+
+func (h *ModerationHandler) Report(w http.ResponseWriter, r *http.Request) {
+	materialID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	userID := r.Context().Value("user_id").(int)
+
+	var req ReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
+		return
+	}
+	id, err := h.reports.Report(r.Context(), materialID, userID, req.Reason, req.Details)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	render.JSON(w, http.StatusCreated, map[string]interface{}{"id": id, "message": "Reported"})
+}
+
+func (h *ModerationHandler) ListReports(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.ReportFilter{
+		Status: q.Get("status"),
+		Reason: q.Get("reason"),
+	}
+	filter.Limit, filter.Offset = parsePagination(r)
+
+	reports, total, err := h.reports.List(r.Context(), filter)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"reports": reports,
+		"total":   total,
+		"limit":   filter.Limit,
+		"offset":  filter.Offset,
+	})
+}
+
+// UpdateReport changes a report's triage state.
+func (h *ModerationHandler) UpdateReport(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	userID := r.Context().Value("user_id").(int)
+
+	var req ReportStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
+		return
+	}
+	if err := h.reports.SetStatus(r.Context(), id, req.Status, userID); err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	h.auditLog.Record(r, audit.Entry{
+		Action:     audit.ActionReportStatus,
+		TargetType: "report",
+		TargetID:   id,
+		After:      map[string]string{"status": req.Status},
+	})
+	render.JSON(w, http.StatusOK, map[string]string{"message": "Report updated"})
+}
+
+// BulkAction applies hide, restore, delete or dismiss to each listed report.
+// Reports are processed independently; failures are returned per ID.
+func (h *ModerationHandler) BulkAction(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	var req BulkActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
+		return
+	}
+	processed, failed, err := h.reports.Apply(r.Context(), req.Action, req.ReportIDs, userID)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	for _, id := range processed {
+		h.auditLog.Record(r, audit.Entry{
+			Action:     audit.ActionModeration,
+			TargetType: "report",
+			TargetID:   id,
+			After:      map[string]string{"action": req.Action},
+		})
+	}
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"action":    req.Action,
+		"processed": processed,
+		"failed":    failed,
+	})
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/api/dto"
+	"github.com/ISKOnnect/iskonnect-web/internal/api/middleware"
+	"github.com/ISKOnnect/iskonnect-web/internal/api/render"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+// Page is the list envelope used from API v2 on.
+type Page struct {
+	Items  interface{} `json:"items"`
+	Total  int         `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+}
+
+// listPage returns the limit and offset a list route should fetch. v1 lists
+// are unpaginated, so they get a zero limit, which repositories read as no
+// limit; v2 takes them from the query string.
+func listPage(r *http.Request) (limit, offset int) {
+	if middleware.APIVersion(r.Context()) < 2 {
+		return 0, 0
+	}
+	return parsePagination(r)
+}
+
+// renderList writes one page of items, fetched with listPage, as a bare
+// array for v1 and as a Page for v2.
+func renderList[T any](w http.ResponseWriter, r *http.Request, items []T, total, limit, offset int) {
+	if items == nil {
+		items = []T{}
+	}
+	if middleware.APIVersion(r.Context()) < 2 {
+		render.JSON(w, http.StatusOK, items)
+		return
+	}
+	render.JSON(w, http.StatusOK, Page{Items: items, Total: total, Limit: limit, Offset: offset})
+}
+
+// embeddedUser is the account returned alongside other data, as in the login
+// and upload responses: the full SelfUser in v1 and dto.User, without email
+// or student number, from v2 on.
+func embeddedUser(r *http.Request, u *models.User) interface{} {
+	if middleware.APIVersion(r.Context()) < 2 {
+		return dto.NewSelfUser(u)
+	}
+	return dto.NewUser(u)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/api/render"
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+	"github.com/ISKOnnect/iskonnect-web/internal/service"
+)
+
+type TokenHandler struct {
+	tokens *service.TokenService
+}
+
+func NewTokenHandler(tokens *service.TokenService) *TokenHandler {
+	return &TokenHandler{tokens: tokens}
+}
+
+type TokenCreateRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty"`
+}
+
+// TokenCreatedResponse is the only response that carries the token itself.
+type TokenCreatedResponse struct {
+	Token    string           `json:"token"`
+	APIToken *models.APIToken `json:"api_token"`
+}
+
+func (h *TokenHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	tokens, err := h.tokens.List(r.Context(), userID)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	render.JSON(w, http.StatusOK, tokens)
+}
+
+func (h *TokenHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var req TokenCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
+		return
+	}
+	secret, token, err := h.tokens.Create(r.Context(), userID, service.TokenRequest{
+		Name:          req.Name,
+		Scopes:        req.Scopes,
+		ExpiresInDays: req.ExpiresInDays,
+	})
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	render.JSON(w, http.StatusCreated, TokenCreatedResponse{Token: secret, APIToken: token})
+}
+
+func (h *TokenHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	userID := r.Context().Value("user_id").(int)
+	if err := h.tokens.Revoke(r.Context(), userID, id); err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	render.JSON(w, http.StatusOK, map[string]string{"message": "Token revoked"})
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/api/dto"
+	"github.com/ISKOnnect/iskonnect-web/internal/api/render"
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+	"github.com/ISKOnnect/iskonnect-web/internal/service"
+)
+
+type UserHandler struct {
+	users *service.UserService
+}
+
+func NewUserHandler(users *service.UserService) *UserHandler {
+	return &UserHandler{users: users}
+}
+
+type ProfileUpdateRequest struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	College   string `json:"college"`
+	Course    string `json:"course"`
+}
+
+func (h *UserHandler) Me(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	user, err := h.users.Get(r.Context(), userID)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	render.JSON(w, http.StatusOK, dto.NewSelfUser(user))
+}
+
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var req ProfileUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
+		return
+	}
+	user, err := h.users.UpdateProfile(r.Context(), userID, service.ProfileUpdate{
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		College:   req.College,
+		Course:    req.Course,
+	})
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	render.JSON(w, http.StatusOK, dto.NewSelfUser(user))
+}
+
+func (h *UserHandler) Privacy(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	settings, err := h.users.Privacy(r.Context(), userID)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	render.JSON(w, http.StatusOK, settings)
+}
+
+func (h *UserHandler) UpdatePrivacy(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var settings models.PrivacySettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		render.Error(w, r, apierror.BadRequest("Invalid request"))
+		return
+	}
+	if err := h.users.UpdatePrivacy(r.Context(), userID, &settings); err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	render.JSON(w, http.StatusOK, settings)
+}
+
+func (h *UserHandler) Profile(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	viewerID := r.Context().Value("user_id").(int)
+	profile, err := h.users.PublicProfile(r.Context(), id, viewerID)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	render.JSON(w, http.StatusOK, dto.NewPublicUser(profile))
+}
@@ -2,45 +2,123 @@ package middleware
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"net/http"
+	"slices"
 	"strings"
 
+	"github.com/ISKOnnect/iskonnect-web/internal/api/render"
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+	"github.com/ISKOnnect/iskonnect-web/internal/logging"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
 	"github.com/ISKOnnect/iskonnect-web/internal/utils"
 )
 
 type AuthMiddleware struct {
-	secret string
+	keys   *utils.Keyring
+	users  models.UserRepository
+	tokens models.APITokenRepository
 }
 
-func NewAuthMiddleware(secret string) *AuthMiddleware {
-	return &AuthMiddleware{secret: secret}
+func NewAuthMiddleware(keys *utils.Keyring, users models.UserRepository, tokens models.APITokenRepository) *AuthMiddleware {
+	return &AuthMiddleware{keys: keys, users: users, tokens: tokens}
 }
 
+// Authenticate accepts a session JWT from the Authorization header or the
+// access_token cookie. Personal access tokens are refused.
 func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
+	return m.authenticate(next, false)
+}
+
+// AuthenticateToken also accepts personal access tokens in the Authorization
+// header. Routes behind it must check the token's scope with RequireScope.
+func (m *AuthMiddleware) AuthenticateToken(next http.Handler) http.Handler {
+	return m.authenticate(next, true)
+}
+
+func (m *AuthMiddleware) authenticate(next http.Handler, allowTokens bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		token := extractToken(r)
+		token, fromCookie := extractToken(r)
 		if token == "" {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			render.Error(w, r, apierror.Unauthorized("Unauthorized"))
+			return
+		}
+		// Bearer tokens are never sent by the browser on its own, so only
+		// cookie-authenticated requests need the CSRF check.
+		if fromCookie && !validCSRF(r) {
+			render.Error(w, r, apierror.Forbidden("Missing or invalid CSRF token"))
 			return
 		}
 
-		claims, err := utils.ValidateJWT(token, m.secret)
+		if strings.HasPrefix(token, models.APITokenPrefix) && !fromCookie {
+			if !allowTokens {
+				render.Error(w, r, apierror.Forbidden("Personal access tokens can't be used for this endpoint"))
+				return
+			}
+			grant, err := m.tokens.Authenticate(r.Context(), utils.HashToken(token))
+			if errors.Is(err, sql.ErrNoRows) {
+				render.Error(w, r, apierror.Unauthorized("Unauthorized"))
+				return
+			}
+			if err != nil {
+				render.Error(w, r, apierror.Internal("Internal error", err))
+				return
+			}
+			logging.SetUserID(r.Context(), grant.UserID)
+			ctx := context.WithValue(r.Context(), "user_id", grant.UserID)
+			ctx = context.WithValue(ctx, "is_student", grant.IsStudent)
+			ctx = context.WithValue(ctx, "is_moderator", grant.IsModerator)
+			ctx = context.WithValue(ctx, "token_scopes", grant.Scopes)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		claims, err := m.keys.ValidateJWT(token)
 		if err != nil {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			render.Error(w, r, apierror.Unauthorized("Unauthorized"))
+			return
+		}
+		// Checked on every request so a password change or deleted account
+		// takes effect immediately rather than when the token expires.
+		version, err := m.users.SessionVersion(r.Context(), claims.UserID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && version != claims.SessionVersion) {
+			render.Error(w, r, apierror.Unauthorized("Session revoked"))
+			return
+		}
+		if err != nil {
+			render.Error(w, r, apierror.Internal("Internal error", err))
 			return
 		}
 
+		logging.SetUserID(r.Context(), claims.UserID)
 		ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
 		ctx = context.WithValue(ctx, "is_student", claims.IsStudent)
+		ctx = context.WithValue(ctx, "is_moderator", claims.IsModerator)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RequireScope rejects personal access tokens that weren't granted scope.
+// Session requests always pass.
+func (m *AuthMiddleware) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, isToken := r.Context().Value("token_scopes").([]string)
+			if isToken && !slices.Contains(scopes, scope) {
+				render.Error(w, r, apierror.Forbidden("Token lacks the "+scope+" scope"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (m *AuthMiddleware) RequireStudent(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		isStudent, ok := r.Context().Value("is_student").(bool)
 		if !ok || !isStudent {
-			http.Error(w, "Forbidden: Students only", http.StatusForbidden)
+			render.Error(w, r, apierror.Forbidden("Forbidden: Students only"))
 			return
 		}
 		next.ServeHTTP(w, r)
@@ -51,19 +129,34 @@ func (m *AuthMiddleware) RequireAdmin(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		isStudent, ok := r.Context().Value("is_student").(bool)
 		if !ok || isStudent {
-			http.Error(w, "Forbidden: Admins only", http.StatusForbidden)
+			render.Error(w, r, apierror.Forbidden("Forbidden: Admins only"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireModerator admits admins and students flagged as moderators.
+func (m *AuthMiddleware) RequireModerator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		isStudent, ok := r.Context().Value("is_student").(bool)
+		isModerator, _ := r.Context().Value("is_moderator").(bool)
+		if !ok || (isStudent && !isModerator) {
+			render.Error(w, r, apierror.Forbidden("Forbidden: Moderators only"))
 			return
 		}
 		next.ServeHTTP(w, r)
 	})
 }
 
-func extractToken(r *http.Request) string {
+// extractToken returns the access token and whether it came from the
+// access_token cookie rather than the Authorization header.
+func extractToken(r *http.Request) (string, bool) {
 	if bearer := r.Header.Get("Authorization"); strings.HasPrefix(bearer, "Bearer ") {
-		return strings.TrimPrefix(bearer, "Bearer ")
+		return strings.TrimPrefix(bearer, "Bearer "), false
 	}
 	if cookie, err := r.Cookie("access_token"); err == nil {
-		return cookie.Value
+		return cookie.Value, true
 	}
-	return ""
+	return "", false
 }
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/api/render"
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+)
+
+// CSRF tokens use the double-submit pattern: login and refresh set a
+// readable csrf_token cookie, and the client echoes it in the X-CSRF-Token
+// header. A cross-site form can make the browser send the cookie but can't
+// read it to set the header.
+const (
+	CSRFCookie = "csrf_token"
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// validCSRF reports whether r may change state. Safe methods always pass;
+// anything else needs a header matching the csrf_token cookie.
+func validCSRF(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	cookie, err := r.Cookie(CSRFCookie)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	header := r.Header.Get(CSRFHeader)
+	return subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) == 1
+}
+
+// RequireCSRF applies the check to routes that act on cookies without going
+// through Authenticate, such as logout and refresh with the refresh_token
+// cookie.
+func RequireCSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !validCSRF(r) {
+			render.Error(w, r, apierror.Forbidden("Missing or invalid CSRF token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/config"
+)
+
+// SecurityHeaders sets HSTS, Content-Security-Policy and
+// X-Content-Type-Options on every response. Handlers serving HTML may
+// replace the CSP with a looser one; frame-ancestors is kept.
+func SecurityHeaders(cfg config.SecurityConfig) func(http.Handler) http.Handler {
+	var hsts string
+	if cfg.HSTSMaxAgeSeconds > 0 {
+		hsts = "max-age=" + strconv.Itoa(cfg.HSTSMaxAgeSeconds)
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+	csp := ContentSecurityPolicy(cfg, cfg.CSP)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			if hsts != "" {
+				h.Set("Strict-Transport-Security", hsts)
+			}
+			if csp != "" {
+				h.Set("Content-Security-Policy", csp)
+			}
+			h.Set("X-Content-Type-Options", "nosniff")
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ContentSecurityPolicy joins policy with the configured frame-ancestors
+// directive.
+func ContentSecurityPolicy(cfg config.SecurityConfig, policy string) string {
+	directives := []string{}
+	if policy = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(policy), ";")); policy != "" {
+		directives = append(directives, policy)
+	}
+	if len(cfg.FrameAncestors) > 0 {
+		directives = append(directives, "frame-ancestors "+strings.Join(cfg.FrameAncestors, " "))
+	}
+	return strings.Join(directives, "; ")
+}
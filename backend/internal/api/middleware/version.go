@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/api/render"
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+)
+
+type versionKey struct{}
+
+// Version records the API version a route was mounted under, so handlers
+// shared between versions can pick the response shape.
+func Version(n int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), versionKey{}, n)))
+		})
+	}
+}
+
+// APIVersion returns the version set by Version, defaulting to 1.
+func APIVersion(ctx context.Context) int {
+	if n, ok := ctx.Value(versionKey{}).(int); ok {
+		return n
+	}
+	return 1
+}
+
+// Deprecation describes a deprecated API surface. Zero times leave the
+// corresponding header out.
+type Deprecation struct {
+	Since  time.Time
+	Sunset time.Time
+	// Successor maps a request path to the path that replaces it.
+	Successor func(path string) string
+}
+
+// Deprecated advertises d on every response using the Deprecation (RFC
+// 9745), Sunset (RFC 8594) and successor-version Link headers. Once the
+// sunset has passed, requests are refused with 410 Gone.
+func Deprecated(d Deprecation) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !d.Since.IsZero() {
+				w.Header().Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+			}
+			if !d.Sunset.IsZero() {
+				w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if d.Successor != nil {
+				w.Header().Set("Link", "<"+d.Successor(r.URL.Path)+`>; rel="successor-version"`)
+			}
+			if !d.Sunset.IsZero() && !time.Now().Before(d.Sunset) {
+				render.Error(w, r, apierror.Gone("This API version has been retired"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,308 @@
+// Package openapi builds an OpenAPI 3.1 document from the chi router and a
+// table of route descriptions. Request and response schemas are reflected
+// from the Go types handlers decode and encode, so renaming a JSON field
+// changes the document with it.
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Route describes one operation. Body and Response are zero values of the
+// types the handler decodes and encodes; leave them nil when there is no
+// body.
+type Route struct {
+	Summary    string
+	Tag        string
+	Public     bool
+	Deprecated bool
+	// Personal marks responses that may carry personal data: the caller's
+	// own account, or anyone's on admin routes. See Document.Exposing.
+	Personal bool
+	// Scope is the personal access token scope that admits the route, if
+	// tokens are accepted at all.
+	Scope    string
+	Query    []Param
+	Body     interface{}
+	Status   int
+	Response interface{}
+	// ContentTypes overrides application/json for non-JSON responses.
+	ContentTypes []string
+}
+
+// Param is a query string parameter.
+type Param struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// Spec maps "METHOD /pattern" keys, as chi reports them, to routes.
+type Spec map[string]Route
+
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Components struct {
+	Schemas         map[string]Schema         `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+type Operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	OperationID string                `json:"operationId"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+
+	personal bool
+}
+
+type Parameter struct {
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Required    bool   `json:"required,omitempty"`
+	Description string `json:"description,omitempty"`
+	Schema      Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema Schema `json:"schema"`
+}
+
+// Schema is a JSON Schema object.
+type Schema map[string]interface{}
+
+var (
+	pathParam = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?\}`)
+
+	// authenticated accepts the access token as a bearer header or cookie.
+	authenticated = []map[string][]string{{"bearerAuth": {}}, {"cookieAuth": {}}}
+)
+
+// Build walks routes and documents every route found in spec. It returns
+// the "METHOD /pattern" keys of routes missing from spec.
+func Build(info Info, routes chi.Routes, spec Spec, problem interface{}) (*Document, []string) {
+	doc := &Document{
+		OpenAPI: "3.1.0",
+		Info:    info,
+		Paths:   map[string]map[string]*Operation{},
+		Components: Components{
+			Schemas: map[string]Schema{},
+			SecuritySchemes: map[string]SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				"tokenAuth": {
+					Type: "http", Scheme: "bearer", BearerFormat: "personal access token",
+					Description: "Created under /api/v1/users/me/tokens. Only accepted where the operation lists a scope.",
+				},
+				"cookieAuth": {
+					Type: "apiKey", In: "cookie", Name: "access_token",
+					Description: "Requests other than GET, HEAD and OPTIONS must also send the csrf_token cookie value in the X-CSRF-Token header.",
+				},
+			},
+		},
+	}
+	gen := &generator{schemas: doc.Components.Schemas}
+	problemSchema := gen.schema(typeOf(problem))
+
+	var undocumented []string
+	chi.Walk(routes, func(method, pattern string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		key := method + " " + pattern
+		route, ok := spec[key]
+		if !ok {
+			undocumented = append(undocumented, key)
+			return nil
+		}
+		path := normalize(pattern)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*Operation{}
+		}
+		doc.Paths[path][strings.ToLower(method)] = gen.operation(method, path, route, problemSchema)
+		return nil
+	})
+	sort.Strings(undocumented)
+	return doc, undocumented
+}
+
+// Exposing returns the "METHOD /path" of every operation not marked
+// Personal whose success response can contain one of fields, at any depth.
+func (d *Document) Exposing(fields []string) []string {
+	var leaks []string
+	for path, ops := range d.Paths {
+		for method, op := range ops {
+			if op.personal {
+				continue
+			}
+			for status, resp := range op.Responses {
+				if status == "default" {
+					continue
+				}
+				for _, media := range resp.Content {
+					if d.reaches(media.Schema, fields, map[string]bool{}) {
+						leaks = append(leaks, strings.ToUpper(method)+" "+path)
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(leaks)
+	return leaks
+}
+
+// reaches reports whether s, following $refs, has a property named in
+// fields.
+func (d *Document) reaches(s Schema, fields []string, seen map[string]bool) bool {
+	if ref, ok := s["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		if seen[name] {
+			return false
+		}
+		seen[name] = true
+		return d.reaches(d.Components.Schemas[name], fields, seen)
+	}
+	if props, ok := s["properties"].(Schema); ok {
+		for name, prop := range props {
+			for _, f := range fields {
+				if name == f {
+					return true
+				}
+			}
+			if d.reaches(prop.(Schema), fields, seen) {
+				return true
+			}
+		}
+	}
+	for _, key := range []string{"items", "additionalProperties"} {
+		if sub, ok := s[key].(Schema); ok && d.reaches(sub, fields, seen) {
+			return true
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]Schema); ok {
+		for _, sub := range anyOf {
+			if d.reaches(sub, fields, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalize strips chi's trailing slash on subrouter roots and any regexp
+// constraints from path parameters.
+func normalize(pattern string) string {
+	if len(pattern) > 1 {
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	return pathParam.ReplaceAllString(pattern, "{$1}")
+}
+
+func (g *generator) operation(method, path string, route Route, problem Schema) *Operation {
+	op := &Operation{
+		Summary:     route.Summary,
+		OperationID: operationID(method, path),
+		Responses:   map[string]Response{},
+		Deprecated:  route.Deprecated,
+		personal:    route.Personal,
+	}
+	if route.Tag != "" {
+		op.Tags = []string{route.Tag}
+	}
+	if !route.Public {
+		op.Security = authenticated
+		if route.Scope != "" {
+			op.Security = append(slices.Clip(authenticated), map[string][]string{"tokenAuth": {route.Scope}})
+		}
+	}
+	for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: Schema{"type": "integer"}})
+	}
+	for _, p := range route.Query {
+		op.Parameters = append(op.Parameters, Parameter{Name: p.Name, In: "query", Description: p.Description, Schema: Schema{"type": p.Type}})
+	}
+	if route.Body != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: g.schema(typeOf(route.Body))}},
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := Response{Description: http.StatusText(status)}
+	switch {
+	case len(route.ContentTypes) > 0:
+		success.Content = map[string]MediaType{}
+		for _, ct := range route.ContentTypes {
+			success.Content[ct] = MediaType{Schema: Schema{"type": "string"}}
+		}
+	case route.Response != nil:
+		success.Content = map[string]MediaType{"application/json": {Schema: g.schema(typeOf(route.Response))}}
+	}
+	op.Responses[itoa(status)] = success
+	op.Responses["default"] = Response{
+		Description: "Error",
+		Content:     map[string]MediaType{"application/problem+json": {Schema: problem}},
+	}
+	return op
+}
+
+// operationID turns "GET /api/materials/{id}/comments" into
+// "getMaterialsIdComments".
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '{' || r == '}' || r == '-' || r == '.' || r == '_' }) {
+		if part == "api" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func itoa(n int) string {
+	const digits = "0123456789"
+	if n < 10 {
+		return digits[n : n+1]
+	}
+	return itoa(n/10) + digits[n%10:n%10+1]
+}
@@ -0,0 +1,26 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:embed redoc.html
+var redocPage []byte
+
+// UIPolicy is the Content-Security-Policy the Redoc page needs: its bundle
+// from the CDN, inline styles, web fonts, a blob worker and the document
+// from this origin.
+const UIPolicy = "default-src 'none'; script-src https://cdn.redoc.ly; style-src 'unsafe-inline' https://fonts.googleapis.com; " +
+	"font-src https://fonts.gstatic.com; img-src data: https:; worker-src blob:; connect-src 'self'"
+
+// UI serves a Redoc page that loads openapi.json relative to its own path,
+// so it must be mounted alongside the document. csp replaces the API's
+// default policy, which would block the page.
+func UI(csp string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", csp)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(redocPage)
+	}
+}
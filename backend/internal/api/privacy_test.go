@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/api/dto"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+// TestNoPersonalDataToOthers calls the routes that show one student's
+// account, uploads and comments to another and checks that none of
+// dto.PIIFields, nor the owner's email or student number, appear in the
+// responses.
+func TestNoPersonalDataToOthers(t *testing.T) {
+	router, store, keys := newTestRouter(t)
+	repos := store.Repositories()
+	ctx := context.Background()
+	owner := newStudent(t, repos, 1)
+	viewer := newStudent(t, repos, 2)
+
+	m := &models.Material{Title: "midterms", Subject: "CMSC 21", FileURL: "https://files.example.com/midterms", Filename: "midterms.pdf", UploaderID: owner.ID}
+	if err := repos.Materials.CreateWithPoints(ctx, m, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Materials.Bookmark(ctx, m.ID, viewer.ID); err != nil {
+		t.Fatal(err)
+	}
+	c := &models.Comment{MaterialID: m.ID, AuthorID: owner.ID, Body: "See page 3"}
+	if err := repos.Comments.Create(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	reply := &models.Comment{MaterialID: m.ID, ParentID: &c.ID, AuthorID: owner.ID, Body: "And page 4"}
+	if err := repos.Comments.Create(ctx, reply); err != nil {
+		t.Fatal(err)
+	}
+	token, err := keys.GenerateJWT(viewer, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := []string{
+		"/materials/",
+		fmt.Sprintf("/materials/%d", m.ID),
+		"/materials/bookmarks",
+		fmt.Sprintf("/materials/%d/comments/", m.ID),
+		fmt.Sprintf("/materials/%d/comments/%d/replies", m.ID, c.ID),
+		fmt.Sprintf("/materials/%d/comments/%d/history", m.ID, c.ID),
+		fmt.Sprintf("/users/%d/profile", owner.ID),
+		"/leaderboard",
+	}
+	forbidden := []string{owner.Email, owner.StudentNumber}
+	for _, field := range dto.PIIFields {
+		forbidden = append(forbidden, `"`+field+`"`)
+	}
+	for _, version := range []string{"/api/v1", "/api/v2"} {
+		for _, path := range paths {
+			rec := do(t, router, http.MethodGet, version+path, token, "", nil)
+			if rec.Code != http.StatusOK {
+				t.Errorf("GET %s%s = %d: %s", version, path, rec.Code, rec.Body)
+				continue
+			}
+			for _, s := range forbidden {
+				if strings.Contains(rec.Body.String(), s) {
+					t.Errorf("GET %s%s contains %s: %s", version, path, s, rec.Body)
+				}
+			}
+		}
+	}
+}
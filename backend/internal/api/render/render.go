@@ -0,0 +1,37 @@
+// Package render writes every API response: JSON bodies for successes and
+// problem+json documents for errors.
+package render
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// JSON writes v as the response body with the given status.
+func JSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("response encode failed", "error", err)
+	}
+}
+
+// Error writes err as an RFC 7807 problem document. Errors that are not an
+// *apierror.Error become a generic 500. Internal errors are logged with
+// their cause, which never reaches the client.
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	e := apierror.From(err)
+	if e.Code == apierror.CodeInternal {
+		slog.ErrorContext(r.Context(), "request failed", "detail", e.Message, "error", errors.Unwrap(e))
+	}
+	problem := e.Problem(r.URL.Path, middleware.GetReqID(r.Context()))
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(e.Status)
+	json.NewEncoder(w).Encode(problem)
+}
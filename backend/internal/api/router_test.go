@@ -0,0 +1,50 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/api/dto"
+	"github.com/ISKOnnect/iskonnect-web/internal/config"
+	"github.com/ISKOnnect/iskonnect-web/internal/email"
+	"github.com/ISKOnnect/iskonnect-web/internal/health"
+	"github.com/ISKOnnect/iskonnect-web/internal/metrics"
+	"github.com/ISKOnnect/iskonnect-web/internal/models/memory"
+	"github.com/ISKOnnect/iskonnect-web/internal/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// newTestRouter builds the full router over an empty memory store.
+func newTestRouter(t *testing.T) (http.Handler, *memory.Store, *utils.Keyring) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Leaderboard.TimeZone = "UTC"
+	keys, err := utils.NewKeyring(cfg.JWT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := memory.NewStore()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := New(store.Repositories(), cfg, keys, logger, metrics.New(nil), health.NewChecker(nil, cfg), &email.Outbox{})
+	return router, store, keys
+}
+
+func TestRoutesDocumented(t *testing.T) {
+	router, _, _ := newTestRouter(t)
+	_, undocumented := buildSpec(router.(chi.Routes))
+	for _, route := range undocumented {
+		t.Errorf("%s is missing from routeDocs", route)
+	}
+}
+
+// TestSpecKeepsPersonalData checks the document: only routes marked Personal
+// may return PIIFields. TestNoPersonalDataToOthers checks the handlers.
+func TestSpecKeepsPersonalData(t *testing.T) {
+	router, _, _ := newTestRouter(t)
+	spec, _ := buildSpec(router.(chi.Routes))
+	for _, route := range spec.Exposing(dto.PIIFields) {
+		t.Errorf("%s exposes personal data to other users", route)
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"github.com/ISKOnnect/iskonnect-web/internal/api/handlers"
+	apiMiddleware "github.com/ISKOnnect/iskonnect-web/internal/api/middleware"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// routes holds the handlers behind every API version. Each version mounts
+// the same tree; handlers that changed shape between versions check
+// apiMiddleware.APIVersion.
+type routes struct {
+	auth           *handlers.AuthHandler
+	comment        *handlers.CommentHandler
+	moderation     *handlers.ModerationHandler
+	audit          *handlers.AuditHandler
+	user           *handlers.UserHandler
+	material       *handlers.MaterialHandler
+	admin          *handlers.AdminHandler
+	token          *handlers.TokenHandler
+	account        *handlers.AccountHandler
+	authMiddleware *apiMiddleware.AuthMiddleware
+}
+
+func (rt *routes) mount(r chi.Router) {
+	// Public routes
+	r.Route("/auth", func(r chi.Router) {
+		r.Post("/register", rt.auth.Register)
+		r.Get("/verify-email", rt.auth.VerifyEmail)
+		r.Get("/confirm-email", rt.auth.ConfirmEmail)
+		r.Post("/login", rt.auth.Login)
+		r.With(apiMiddleware.RequireCSRF).Post("/logout", rt.auth.Logout)
+		r.With(apiMiddleware.RequireCSRF).Post("/refresh", rt.auth.RefreshToken)
+		r.Post("/forgot-password", rt.auth.ForgotPassword)
+		r.Post("/verify-otp", rt.auth.VerifyOTP)
+		r.Post("/reset-password", rt.auth.ResetPassword)
+	})
+
+	// Materials routes that name a scope also accept personal access
+	// tokens; the rest are session-only.
+	r.Route("/materials", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(rt.authMiddleware.AuthenticateToken)
+			r.Use(rt.authMiddleware.RequireStudent)
+
+			read := rt.authMiddleware.RequireScope(models.ScopeMaterialsRead)
+			r.With(read).Get("/", rt.material.List)
+			r.With(rt.authMiddleware.RequireScope(models.ScopeMaterialsUpload)).Post("/", rt.material.Create)
+			r.With(read).Get("/bookmarks", rt.material.Bookmarks)
+			r.With(read).Get("/{id}", rt.material.Get)
+			r.With(rt.authMiddleware.RequireScope(models.ScopeMaterialsVote)).Post("/{id}/vote", rt.material.Vote)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(rt.authMiddleware.Authenticate)
+			r.Use(rt.authMiddleware.RequireStudent)
+
+			r.Post("/{id}/bookmark", rt.material.Bookmark)
+			r.Post("/{id}/report", rt.moderation.Report)
+
+			r.Route("/{id}/comments", func(r chi.Router) {
+				r.Get("/", rt.comment.List)
+				r.Post("/", rt.comment.Create)
+				r.Put("/{commentID}", rt.comment.Update)
+				r.Delete("/{commentID}", rt.comment.Delete)
+				r.Get("/{commentID}/replies", rt.comment.ListReplies)
+				r.Get("/{commentID}/history", rt.comment.History)
+			})
+		})
+	})
+
+	// Authenticated routes
+	r.Group(func(r chi.Router) {
+		r.Use(rt.authMiddleware.Authenticate)
+
+		// User routes (all users)
+		r.Get("/users/me", rt.user.Me)
+		r.Put("/users/me", rt.user.UpdateMe)
+		r.Delete("/users/me", rt.account.Delete)
+		r.Get("/users/me/export", rt.account.Export)
+		r.Get("/users/me/privacy", rt.user.Privacy)
+		r.Put("/users/me/privacy", rt.user.UpdatePrivacy)
+		r.Post("/users/me/password", rt.auth.ChangePassword)
+		r.Post("/users/me/email", rt.auth.ChangeEmail)
+		r.Get("/users/me/tokens", rt.token.List)
+		r.Post("/users/me/tokens", rt.token.Create)
+		r.Delete("/users/me/tokens/{id}", rt.token.Revoke)
+		r.Get("/users/{id}/profile", rt.user.Profile)
+
+		// Student-only routes
+		r.Group(func(r chi.Router) {
+			r.Use(rt.authMiddleware.RequireStudent)
+
+			r.Get("/leaderboard", rt.material.Leaderboard)
+		})
+
+		// Moderator routes (admins and student moderators)
+		r.Group(func(r chi.Router) {
+			r.Use(rt.authMiddleware.RequireModerator)
+
+			r.Get("/moderation/reports", rt.moderation.ListReports)
+			r.Put("/moderation/reports/{id}", rt.moderation.UpdateReport)
+			r.Post("/moderation/actions", rt.moderation.BulkAction)
+		})
+
+		// Admin-only routes
+		r.Group(func(r chi.Router) {
+			r.Use(rt.authMiddleware.RequireAdmin)
+
+			r.Get("/admin/users", rt.admin.ListUsers)
+			r.Delete("/admin/users/{id}", rt.admin.DeleteUser)
+			r.Get("/admin/users/deleted", rt.admin.ListDeletedUsers)
+			r.Post("/admin/users/{id}/restore", rt.admin.RestoreUser)
+			r.Put("/admin/users/{id}/moderator", rt.admin.SetModerator)
+			r.Get("/admin/materials", rt.admin.ListMaterials)
+			r.Put("/admin/materials/{id}", rt.admin.UpdateMaterial)
+			r.Delete("/admin/materials/{id}", rt.admin.DeleteMaterial)
+			r.Get("/admin/materials/deleted", rt.admin.ListDeletedMaterials)
+			r.Post("/admin/materials/{id}/restore", rt.admin.RestoreMaterial)
+			r.Get("/admin/audit", rt.audit.List)
+			r.Get("/admin/audit/export", rt.audit.Export)
+		})
+	})
+}
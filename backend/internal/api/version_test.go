@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+	"github.com/ISKOnnect/iskonnect-web/internal/utils"
+)
+
+const testPassword = "correct horse battery"
+
+// newStudent registers a verified student who can log in with testPassword.
+func newStudent(t *testing.T, repos models.Repositories, n int) *models.User {
+	t.Helper()
+	hash, err := utils.HashPassword(context.Background(), testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	u := &models.User{
+		StudentNumber: fmt.Sprintf("2020-%05d-MN-0", n),
+		FirstName:     "Juan",
+		LastName:      "Dela Cruz",
+		Email:         fmt.Sprintf("student%d@up.edu.ph", n),
+		IsStudent:     true,
+		EmailVerified: true,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := repos.Users.Register(context.Background(), u, hash, "verify", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// do serves one request, authenticated as token unless it is empty, and
+// decodes the JSON response into out.
+func do(t *testing.T, h http.Handler, method, path, token, body string, out interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decoding %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec
+}
+
+func TestLoginUserByVersion(t *testing.T) {
+	router, store, _ := newTestRouter(t)
+	u := newStudent(t, store.Repositories(), 1)
+	body := `{"student_number": "` + u.StudentNumber + `", "password": "` + testPassword + `"}`
+
+	tests := []struct {
+		path      string
+		wantEmail bool
+	}{
+		{"/api/v1/auth/login", true},
+		{"/api/v2/auth/login", false},
+	}
+	for _, tt := range tests {
+		var resp struct {
+			User map[string]interface{} `json:"user"`
+		}
+		if rec := do(t, router, http.MethodPost, tt.path, "", body, &resp); rec.Code != http.StatusOK {
+			t.Fatalf("%s = %d: %s", tt.path, rec.Code, rec.Body)
+		}
+		if _, ok := resp.User["email"]; ok != tt.wantEmail {
+			t.Errorf("%s user = %v, want email included: %v", tt.path, resp.User, tt.wantEmail)
+		}
+		if _, ok := resp.User["student_number"]; ok != tt.wantEmail {
+			t.Errorf("%s user = %v, want student_number included: %v", tt.path, resp.User, tt.wantEmail)
+		}
+	}
+}
+
+func TestMaterialListByVersion(t *testing.T) {
+	router, store, keys := newTestRouter(t)
+	repos := store.Repositories()
+	u := newStudent(t, repos, 1)
+	for _, title := range []string{"first", "second", "third"} {
+		m := &models.Material{Title: title, Subject: "CMSC 21", FileURL: "https://files.example.com/" + title, Filename: title + ".pdf", UploaderID: u.ID}
+		if err := repos.Materials.Create(context.Background(), m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	token, err := keys.GenerateJWT(u, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v1 []*models.Material
+	if rec := do(t, router, http.MethodGet, "/api/v1/materials/?limit=1", token, "", &v1); rec.Code != http.StatusOK {
+		t.Fatalf("v1 list = %d: %s", rec.Code, rec.Body)
+	}
+	if len(v1) != 3 {
+		t.Errorf("v1 list returned %d materials, want all 3 regardless of limit", len(v1))
+	}
+
+	var v2 struct {
+		Items  []*models.Material `json:"items"`
+		Total  int                `json:"total"`
+		Limit  int                `json:"limit"`
+		Offset int                `json:"offset"`
+	}
+	if rec := do(t, router, http.MethodGet, "/api/v2/materials/?limit=2&offset=1", token, "", &v2); rec.Code != http.StatusOK {
+		t.Fatalf("v2 list = %d: %s", rec.Code, rec.Body)
+	}
+	if v2.Total != 3 || v2.Limit != 2 || v2.Offset != 1 || len(v2.Items) != 2 || v2.Items[0].Title != "second" {
+		t.Errorf("v2 page = %+v, want items 2-3 of 3 starting with second", v2)
+	}
+
+	var empty struct {
+		Items []*models.Material `json:"items"`
+		Total int                `json:"total"`
+	}
+	rec := do(t, router, http.MethodGet, "/api/v2/materials/bookmarks", token, "", &empty)
+	if rec.Code != http.StatusOK || empty.Items == nil || empty.Total != 0 {
+		t.Errorf("v2 bookmarks = %d %s, want an empty page", rec.Code, rec.Body)
+	}
+}
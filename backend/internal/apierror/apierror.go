@@ -0,0 +1,143 @@
+// Package apierror defines the typed errors handlers return to clients. Each
+// carries a stable machine-readable code and is rendered as an RFC 7807
+// problem document by the render package.
+package apierror
+
+import (
+	"errors"
+	"net/http"
+)
+
+type Code string
+
+const (
+	CodeBadRequest   Code = "bad_request"
+	CodeValidation   Code = "validation"
+	CodeUnauthorized Code = "unauthorized"
+	CodeForbidden    Code = "forbidden"
+	CodeNotFound     Code = "not_found"
+	CodeConflict     Code = "conflict"
+	CodeGone         Code = "gone"
+	CodeRateLimited  Code = "rate_limited"
+	CodeInternal     Code = "internal"
+)
+
+// TypeBase prefixes the code to form the problem type URI.
+const TypeBase = "https://iskonnect.com/problems/"
+
+// FieldError describes one invalid request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type Error struct {
+	Code    Code
+	Status  int
+	Message string
+	Fields  []FieldError
+
+	cause error
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return string(e.Code) + ": " + e.Message + ": " + e.cause.Error()
+	}
+	return string(e.Code) + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+func newError(code Code, status int, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message}
+}
+
+// BadRequest is for requests that can't be parsed at all, such as malformed
+// JSON or a non-numeric ID.
+func BadRequest(message string) *Error {
+	return newError(CodeBadRequest, http.StatusBadRequest, message)
+}
+
+// Validation is for well-formed requests with invalid values.
+func Validation(message string, fields ...FieldError) *Error {
+	e := newError(CodeValidation, http.StatusBadRequest, message)
+	e.Fields = fields
+	return e
+}
+
+// Invalid is a validation error for a single field.
+func Invalid(field, message string) *Error {
+	return Validation(message, FieldError{Field: field, Message: message})
+}
+
+func Unauthorized(message string) *Error {
+	return newError(CodeUnauthorized, http.StatusUnauthorized, message)
+}
+
+func Forbidden(message string) *Error {
+	return newError(CodeForbidden, http.StatusForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return newError(CodeNotFound, http.StatusNotFound, message)
+}
+
+func Conflict(message string) *Error {
+	return newError(CodeConflict, http.StatusConflict, message)
+}
+
+// Gone is for endpoints that have been retired.
+func Gone(message string) *Error {
+	return newError(CodeGone, http.StatusGone, message)
+}
+
+func RateLimited(message string) *Error {
+	return newError(CodeRateLimited, http.StatusTooManyRequests, message)
+}
+
+// Internal is for failures the client can't fix. message is shown to the
+// client; cause, which may be nil, is only logged.
+func Internal(message string, cause error) *Error {
+	e := newError(CodeInternal, http.StatusInternalServerError, message)
+	e.cause = cause
+	return e
+}
+
+// From returns err as an *Error, treating anything untyped as internal.
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return Internal("Internal error", err)
+}
+
+// Problem is the RFC 7807 response body. Code and Errors are extension
+// members.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      Code         `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// Problem builds the response body for e. instance is the request path.
+func (e *Error) Problem(instance, requestID string) Problem {
+	return Problem{
+		Type:      TypeBase + string(e.Code),
+		Title:     http.StatusText(e.Status),
+		Status:    e.Status,
+		Detail:    e.Message,
+		Instance:  instance,
+		Code:      e.Code,
+		RequestID: requestID,
+		Errors:    e.Fields,
+	}
+}
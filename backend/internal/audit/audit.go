@@ -0,0 +1,157 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"reflect"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/logging"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	ActionUserDelete         = "user.delete"
+	ActionUserRestore        = "user.restore"
+	ActionUserSetModerator   = "user.set_moderator"
+	ActionUserExport         = "user.export"
+	ActionUserSelfDelete     = "user.self_delete"
+	ActionMaterialUpdate     = "material.update"
+	ActionMaterialDelete     = "material.delete"
+	ActionMaterialRestore    = "material.restore"
+	ActionReportStatus       = "report.status"
+	ActionModeration         = "report.moderate"
+	ActionEmailVerified      = "auth.email_verified"
+	ActionPasswordReset      = "auth.password_reset"
+	ActionPasswordResetOTP   = "auth.password_reset_otp_verified"
+	ActionPasswordResetStart = "auth.password_reset_requested"
+	ActionPasswordChange     = "auth.password_changed"
+	ActionEmailChangeStart   = "auth.email_change_requested"
+	ActionEmailChanged       = "auth.email_changed"
+)
+
+// redactedFields never reach the audit log, even if a caller passes a struct
+// that carries them. Secrets are listed alongside personal data: audit rows
+// are append-only and outlive the accounts they describe, so a diff records
+// that such a field changed but never its values.
+var redactedFields = map[string]bool{
+	"password":       true,
+	"password_hash":  true,
+	"token":          true,
+	"otp":            true,
+	"reset_token":    true,
+	"access_token":   true,
+	"refresh_token":  true,
+	"email":          true,
+	"student_number": true,
+	"first_name":     true,
+	"last_name":      true,
+	"handle":         true,
+}
+
+type Entry struct {
+	// ActorID defaults to the authenticated user in the request context.
+	ActorID    int
+	Action     string
+	TargetType string
+	TargetID   int
+	Before     interface{}
+	After      interface{}
+}
+
+type Logger struct {
+	model models.AuditRepository
+	log   *slog.Logger
+}
+
+func NewLogger(events models.AuditRepository, logger *slog.Logger) *Logger {
+	return &Logger{model: events, log: logger}
+}
+
+// Record appends an audit event for the request. Audit failures are logged
+// but never fail the request that triggered them. A nil Logger discards
+// events.
+func (l *Logger) Record(r *http.Request, e Entry) {
+	if l == nil {
+		return
+	}
+	event := &models.AuditEvent{
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		RequestID:  middleware.GetReqID(r.Context()),
+		IP:         logging.ClientIP(r),
+	}
+	actorID := e.ActorID
+	if actorID == 0 {
+		actorID, _ = r.Context().Value("user_id").(int)
+	}
+	if actorID != 0 {
+		event.ActorID = &actorID
+	}
+	if e.TargetID != 0 {
+		targetID := e.TargetID
+		event.TargetID = &targetID
+	}
+
+	diff, err := Diff(e.Before, e.After)
+	if err != nil {
+		l.log.ErrorContext(r.Context(), "audit diff failed", "action", e.Action, "error", err)
+	}
+	event.Diff = diff
+
+	if err := l.model.Create(context.WithoutCancel(r.Context()), event); err != nil {
+		l.log.ErrorContext(r.Context(), "audit write failed", "action", e.Action, "error", err)
+	}
+}
+
+// Diff returns a JSON object mapping each changed field to its before and
+// after values. Before and After are compared by their JSON representation;
+// either may be nil for creations and deletions. Values of redactedFields are
+// compared but written as "[REDACTED]".
+func Diff(before, after interface{}) (json.RawMessage, error) {
+	b, err := toFields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := toFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]map[string]interface{}{}
+	for k, bv := range b {
+		if av, ok := a[k]; !ok || !reflect.DeepEqual(av, bv) {
+			changes[k] = map[string]interface{}{"before": redact(k, bv), "after": redact(k, a[k])}
+		}
+	}
+	for k, av := range a {
+		if _, ok := b[k]; !ok {
+			changes[k] = map[string]interface{}{"before": nil, "after": redact(k, av)}
+		}
+	}
+	return json.Marshal(changes)
+}
+
+func redact(key string, v interface{}) interface{} {
+	if v == nil || !redactedFields[key] {
+		return v
+	}
+	return "[REDACTED]"
+}
+
+func toFields(v interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if v == nil {
+		return fields, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -1,90 +1,241 @@
 package config
 
-import (
-	"os"
-	"strconv"
-)
+import "time"
 
+// DefaultJWTSecret is the development signing key. Validate rejects it in
+// production.
+const DefaultJWTSecret = "your-secret-key"
+
+// Config is the effective configuration. Load builds it from Default,
+// then an optional YAML file, then environment variables, then flags.
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Email    EmailConfig
+	Server      ServerConfig      `yaml:"server"`
+	Database    DatabaseConfig    `yaml:"database"`
+	JWT         JWTConfig         `yaml:"jwt"`
+	Email       EmailConfig       `yaml:"email"`
+	Retention   RetentionConfig   `yaml:"retention"`
+	Leaderboard LeaderboardConfig `yaml:"leaderboard"`
+	Storage     StorageConfig     `yaml:"storage"`
+	Metrics     MetricsConfig     `yaml:"metrics"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Health      HealthConfig      `yaml:"health"`
+	API         APIConfig         `yaml:"api"`
+	CORS        CORSConfig        `yaml:"cors"`
+	Cookie      CookieConfig      `yaml:"cookie"`
+	Security    SecurityConfig    `yaml:"security"`
 }
 
+// ServerConfig controls the HTTP server. PublicURL is the scheme and host
+// clients reach the API at; links sent in emails start with it.
 type ServerConfig struct {
-	Port                   string
-	Environment            string
-	ReadTimeoutSeconds     int
-	WriteTimeoutSeconds    int
-	IdleTimeoutSeconds     int
-	ShutdownTimeoutSeconds int
+	Port                   string `yaml:"port"`
+	PublicURL              string `yaml:"public_url"`
+	Environment            string `yaml:"environment"`
+	ReadTimeoutSeconds     int    `yaml:"read_timeout_seconds"`
+	WriteTimeoutSeconds    int    `yaml:"write_timeout_seconds"`
+	IdleTimeoutSeconds     int    `yaml:"idle_timeout_seconds"`
+	ShutdownTimeoutSeconds int    `yaml:"shutdown_timeout_seconds"`
+	LogLevel               string `yaml:"log_level"`
+	LogFormat              string `yaml:"log_format"`
 }
 
 type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	DBName   string `yaml:"name"`
+	SSLMode  string `yaml:"ssl_mode"`
+
+	QueryTimeoutSeconds int `yaml:"query_timeout_seconds"`
+	SlowQueryMillis     int `yaml:"slow_query_millis"`
 }
 
+// JWTConfig describes the token keyring. Secret, when set, is the HS256 key
+// with ID "default". Keys adds more; every key verifies, and SigningKey
+// names the one that signs new tokens. To rotate, add the new key, switch
+// SigningKey to it, and drop the old key once its tokens have expired.
 type JWTConfig struct {
-	Secret string
+	Secret     string   `yaml:"secret"`
+	SigningKey string   `yaml:"signing_key"`
+	Keys       []JWTKey `yaml:"keys"`
+	Issuer     string   `yaml:"issuer"`
+	Audience   string   `yaml:"audience"`
+}
+
+// JWTKey is one keyring entry. HS256 keys take a secret; RS256 and EdDSA
+// keys take a PEM file holding a private key, or just a public key to
+// verify tokens signed elsewhere.
+type JWTKey struct {
+	ID        string `yaml:"id"`
+	Algorithm string `yaml:"algorithm"`
+	Secret    string `yaml:"secret,omitempty"`
+	KeyFile   string `yaml:"key_file,omitempty"`
 }
 
 type EmailConfig struct {
-	SMTPHost     string
-	SMTPPort     string
-	SMTPUser     string
-	SMTPPassword string
-	FromEmail    string
-	FromName     string
+	SMTPHost     string `yaml:"smtp_host"`
+	SMTPPort     string `yaml:"smtp_port"`
+	SMTPUser     string `yaml:"smtp_user"`
+	SMTPPassword string `yaml:"smtp_password"`
+	FromEmail    string `yaml:"from_email"`
+	FromName     string `yaml:"from_name"`
+}
+
+// MetricsConfig controls how /metrics is exposed. With ListenAddr set it is
+// served on that address only; otherwise it is mounted on the API server and
+// requires Token as a bearer token. With neither set it is not exposed.
+type MetricsConfig struct {
+	ListenAddr string `yaml:"listen_addr"`
+	Token      string `yaml:"token"`
+}
+
+// TracingConfig selects the OpenTelemetry exporter: "otlp", "stdout" or
+// "none".
+type TracingConfig struct {
+	Exporter     string  `yaml:"exporter"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint"`
+	ServiceName  string  `yaml:"service_name"`
+	SampleRatio  float64 `yaml:"sample_ratio"`
+}
+
+// HealthConfig controls the readiness probe. DrainSeconds is how long
+// readiness fails before shutdown begins, giving load balancers time to
+// stop sending traffic.
+type HealthConfig struct {
+	CheckSMTP    bool `yaml:"check_smtp"`
+	DrainSeconds int  `yaml:"drain_seconds"`
+}
+
+// APIConfig schedules the retirement of old API versions. Dates are
+// YYYY-MM-DD; a zero date leaves the header out. The unversioned /api
+// paths are always deprecated in favor of /api/v1; v1 is deprecated only
+// once V1Deprecated is set. Past its sunset a version answers 410 Gone.
+type APIConfig struct {
+	LegacyDeprecated time.Time `yaml:"legacy_deprecated,omitempty"`
+	LegacySunset     time.Time `yaml:"legacy_sunset,omitempty"`
+	V1Deprecated     time.Time `yaml:"v1_deprecated,omitempty"`
+	V1Sunset         time.Time `yaml:"v1_sunset,omitempty"`
+}
+
+// CORSConfig lists the browser origins allowed to call the API with
+// credentials.
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	MaxAgeSeconds  int      `yaml:"max_age_seconds"`
+}
+
+// CookieConfig sets the attributes of the auth cookies. SameSite is
+// "strict", "lax" or "none"; "none" requires Secure.
+type CookieConfig struct {
+	Domain   string `yaml:"domain"`
+	SameSite string `yaml:"same_site"`
+	Secure   bool   `yaml:"secure"`
+}
+
+// SecurityConfig drives the security headers sent on every response. A zero
+// HSTSMaxAgeSeconds leaves Strict-Transport-Security out, which is what
+// plain-HTTP development needs. FrameAncestors is appended to CSP as its
+// frame-ancestors directive.
+type SecurityConfig struct {
+	HSTSMaxAgeSeconds     int      `yaml:"hsts_max_age_seconds"`
+	HSTSIncludeSubdomains bool     `yaml:"hsts_include_subdomains"`
+	CSP                   string   `yaml:"csp"`
+	FrameAncestors        []string `yaml:"frame_ancestors"`
+}
+
+// RetentionConfig controls purging of soft-deleted rows. DeletedUserContent
+// decides what happens to a purged user's materials, comments and votes:
+// "anonymize" keeps them under a scrubbed placeholder account, "delete"
+// removes them with the user.
+type RetentionConfig struct {
+	GracePeriodDays    int    `yaml:"grace_period_days"`
+	IntervalMinutes    int    `yaml:"interval_minutes"`
+	DeletedUserContent string `yaml:"deleted_user_content"`
 }
 
-func New() *Config {
+// StorageConfig names the directory that material files are served from and
+// the URL prefix they are served under. The retention job deletes purged
+// materials' files from it; files at other URLs are hosted elsewhere and
+// are left to that host.
+type StorageConfig struct {
+	PublicURL string `yaml:"public_url"`
+	Dir       string `yaml:"dir"`
+}
+
+// LeaderboardConfig places the leaderboard's week, month and semester
+// windows. TimeZone is an IANA name; SemesterStarts are MM-DD dates.
+type LeaderboardConfig struct {
+	TimeZone       string   `yaml:"time_zone"`
+	SemesterStarts []string `yaml:"semester_starts"`
+}
+
+// Default returns the built-in settings, suitable for local development.
+func Default() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port:                   getEnv("SERVER_PORT", "8080"),
-			Environment:            getEnv("ENVIRONMENT", "development"),
-			ReadTimeoutSeconds:     getEnvAsInt("SERVER_READ_TIMEOUT", 10),
-			WriteTimeoutSeconds:    getEnvAsInt("SERVER_WRITE_TIMEOUT", 10),
-			IdleTimeoutSeconds:     getEnvAsInt("SERVER_IDLE_TIMEOUT", 120),
-			ShutdownTimeoutSeconds: getEnvAsInt("SERVER_SHUTDOWN_TIMEOUT", 10),
+			Port:                   "8080",
+			PublicURL:              "http://localhost:8080",
+			Environment:            "development",
+			ReadTimeoutSeconds:     10,
+			WriteTimeoutSeconds:    10,
+			IdleTimeoutSeconds:     120,
+			ShutdownTimeoutSeconds: 10,
+			LogLevel:               "info",
+			LogFormat:              "json",
 		},
 		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "postgres"),
-			DBName:   getEnv("DB_NAME", "iskonnect"),
-			SSLMode:  getEnv("DB_SSL_MODE", "disable"),
+			Host:     "localhost",
+			Port:     "5432",
+			User:     "postgres",
+			Password: "postgres",
+			DBName:   "iskonnect",
+			SSLMode:  "disable",
+
+			QueryTimeoutSeconds: 5,
+			SlowQueryMillis:     200,
 		},
 		JWT: JWTConfig{
-			Secret: getEnv("JWT_SECRET", "your-secret-key"),
+			Secret:   DefaultJWTSecret,
+			Issuer:   "iskonnect",
+			Audience: "iskonnect-api",
 		},
 		Email: EmailConfig{
-			SMTPHost:     getEnv("SMTP_HOST", "smtp.gmail.com"),
-			SMTPPort:     getEnv("SMTP_PORT", "587"),
-			SMTPUser:     getEnv("SMTP_USER", ""),
-			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
-			FromEmail:    getEnv("FROM_EMAIL", "no-reply@iskonnect.com"),
-			FromName:     getEnv("FROM_NAME", "ISKOnnect"),
+			SMTPHost:  "smtp.gmail.com",
+			SMTPPort:  "587",
+			FromEmail: "no-reply@iskonnect.com",
+			FromName:  "ISKOnnect",
+		},
+		Retention: RetentionConfig{
+			GracePeriodDays:    30,
+			IntervalMinutes:    60,
+			DeletedUserContent: "anonymize",
+		},
+		Leaderboard: LeaderboardConfig{
+			TimeZone:       "Asia/Manila",
+			SemesterStarts: []string{"01-01", "08-01"},
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "iskonnect-api",
+			SampleRatio: 1,
+		},
+		Health: HealthConfig{
+			DrainSeconds: 5,
+		},
+		API: APIConfig{
+			LegacyDeprecated: time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"http://localhost:3000"},
+			MaxAgeSeconds:  300,
+		},
+		Cookie: CookieConfig{
+			SameSite: "strict",
+		},
+		Security: SecurityConfig{
+			CSP:            "default-src 'none'",
+			FrameAncestors: []string{"'none'"},
 		},
 	}
 }
-
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
-
-func getEnvAsInt(key string, defaultValue int) int {
-	if value, err := strconv.Atoi(getEnv(key, "")); err == nil {
-		return value
-	}
-	return defaultValue
-}
@@ -0,0 +1,210 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// setting binds one field to its environment variable. Its flag name is the
+// variable in lower case with dashes, so DB_HOST is -db-host.
+type setting struct {
+	env   string
+	field interface{}
+	usage string
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"SERVER_PORT", &c.Server.Port, "HTTP listen port"},
+		{"SERVER_PUBLIC_URL", &c.Server.PublicURL, "URL clients reach the API at, used in email links"},
+		{"ENVIRONMENT", &c.Server.Environment, "development, staging or production"},
+		{"SERVER_READ_TIMEOUT", &c.Server.ReadTimeoutSeconds, "request read timeout in seconds"},
+		{"SERVER_WRITE_TIMEOUT", &c.Server.WriteTimeoutSeconds, "response write timeout in seconds"},
+		{"SERVER_IDLE_TIMEOUT", &c.Server.IdleTimeoutSeconds, "keep-alive idle timeout in seconds"},
+		{"SERVER_SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeoutSeconds, "graceful shutdown timeout in seconds"},
+		{"LOG_LEVEL", &c.Server.LogLevel, "debug, info, warn or error"},
+		{"LOG_FORMAT", &c.Server.LogFormat, "json or text"},
+
+		{"DB_HOST", &c.Database.Host, "Postgres host"},
+		{"DB_PORT", &c.Database.Port, "Postgres port"},
+		{"DB_USER", &c.Database.User, "Postgres user"},
+		{"DB_PASSWORD", &c.Database.Password, "Postgres password"},
+		{"DB_NAME", &c.Database.DBName, "Postgres database"},
+		{"DB_SSL_MODE", &c.Database.SSLMode, "Postgres sslmode"},
+		{"DB_QUERY_TIMEOUT", &c.Database.QueryTimeoutSeconds, "per-query timeout in seconds, 0 to disable"},
+		{"DB_SLOW_QUERY_MS", &c.Database.SlowQueryMillis, "slow query log threshold in milliseconds, 0 to disable"},
+
+		{"JWT_SECRET", &c.JWT.Secret, "HS256 secret for the \"default\" key, empty to disable"},
+		{"JWT_SIGNING_KEY", &c.JWT.SigningKey, "ID of the key that signs new tokens"},
+		{"JWT_ISSUER", &c.JWT.Issuer, "iss claim issued and required"},
+		{"JWT_AUDIENCE", &c.JWT.Audience, "aud claim issued and required"},
+
+		{"SMTP_HOST", &c.Email.SMTPHost, "SMTP host"},
+		{"SMTP_PORT", &c.Email.SMTPPort, "SMTP port"},
+		{"SMTP_USER", &c.Email.SMTPUser, "SMTP user"},
+		{"SMTP_PASSWORD", &c.Email.SMTPPassword, "SMTP password"},
+		{"FROM_EMAIL", &c.Email.FromEmail, "sender address"},
+		{"FROM_NAME", &c.Email.FromName, "sender name"},
+
+		{"RETENTION_GRACE_DAYS", &c.Retention.GracePeriodDays, "days before soft-deleted rows are purged"},
+		{"RETENTION_INTERVAL_MINUTES", &c.Retention.IntervalMinutes, "minutes between purge runs"},
+		{"RETENTION_DELETED_USER_CONTENT", &c.Retention.DeletedUserContent, "anonymize or delete a purged user's content"},
+
+		{"LEADERBOARD_TIME_ZONE", &c.Leaderboard.TimeZone, "IANA time zone for leaderboard windows"},
+		{"LEADERBOARD_SEMESTER_STARTS", &c.Leaderboard.SemesterStarts, "comma-separated MM-DD semester start dates"},
+
+		{"STORAGE_PUBLIC_URL", &c.Storage.PublicURL, "URL prefix material files are served under"},
+		{"STORAGE_DIR", &c.Storage.Dir, "directory material files are served from"},
+
+		{"METRICS_ADDR", &c.Metrics.ListenAddr, "separate listen address for /metrics"},
+		{"METRICS_TOKEN", &c.Metrics.Token, "bearer token for /metrics on the API server"},
+
+		{"OTEL_TRACES_EXPORTER", &c.Tracing.Exporter, "otlp, stdout or none"},
+		{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", &c.Tracing.OTLPEndpoint, "OTLP/HTTP traces endpoint URL"},
+		{"OTEL_SERVICE_NAME", &c.Tracing.ServiceName, "service.name resource attribute"},
+		{"OTEL_TRACES_SAMPLE_RATIO", &c.Tracing.SampleRatio, "fraction of root traces sampled"},
+
+		{"HEALTH_CHECK_SMTP", &c.Health.CheckSMTP, "include SMTP in the readiness check"},
+		{"HEALTH_DRAIN_SECONDS", &c.Health.DrainSeconds, "seconds readiness fails before shutdown"},
+
+		{"API_LEGACY_DEPRECATED", &c.API.LegacyDeprecated, "date the unversioned API was deprecated"},
+		{"API_LEGACY_SUNSET", &c.API.LegacySunset, "date the unversioned API is retired"},
+		{"API_V1_DEPRECATED", &c.API.V1Deprecated, "date v1 was deprecated"},
+		{"API_V1_SUNSET", &c.API.V1Sunset, "date v1 is retired"},
+
+		{"CORS_ALLOWED_ORIGINS", &c.CORS.AllowedOrigins, "comma-separated origins allowed by CORS"},
+		{"CORS_MAX_AGE", &c.CORS.MaxAgeSeconds, "seconds browsers may cache a preflight response"},
+
+		{"COOKIE_DOMAIN", &c.Cookie.Domain, "Domain attribute of auth cookies"},
+		{"COOKIE_SAME_SITE", &c.Cookie.SameSite, "strict, lax or none"},
+		{"COOKIE_SECURE", &c.Cookie.Secure, "send auth cookies over HTTPS only"},
+
+		{"HSTS_MAX_AGE", &c.Security.HSTSMaxAgeSeconds, "Strict-Transport-Security max-age, 0 to disable"},
+		{"HSTS_INCLUDE_SUBDOMAINS", &c.Security.HSTSIncludeSubdomains, "add includeSubDomains to HSTS"},
+		{"CSP", &c.Security.CSP, "Content-Security-Policy, without frame-ancestors"},
+		{"CSP_FRAME_ANCESTORS", &c.Security.FrameAncestors, "comma-separated frame-ancestors sources"},
+	}
+}
+
+// Load builds the configuration from Default, the YAML file named by the
+// -config flag or CONFIG_FILE, environment variables and finally the flags
+// in args, which it registers on fs. A variable that is set but empty clears
+// the setting. A value that does not parse is an error rather than a silent
+// fallback. Load does not call Validate.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	path := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML config file")
+	type override struct {
+		s     setting
+		value string
+	}
+	var overrides []override
+	for _, s := range settings {
+		s := s
+		fs.Func(flagName(s.env), s.usage, func(v string) error {
+			overrides = append(overrides, override{s, v})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *path != "" {
+		if err := cfg.loadFile(*path); err != nil {
+			return nil, err
+		}
+	}
+	var errs []error
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.env); ok {
+			if err := set(s.field, v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+		}
+	}
+	for _, o := range overrides {
+		if err := set(o.s.field, o.value); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: %w", flagName(o.s.env), err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func flagName(env string) string {
+	return strings.ToLower(strings.ReplaceAll(env, "_", "-"))
+}
+
+// set parses v into the field pointer according to its type. An empty v
+// clears the field, so an empty variable or flag can undo the config file.
+func set(field interface{}, v string) error {
+	if v == "" {
+		reflect.ValueOf(field).Elem().SetZero()
+		return nil
+	}
+	switch p := field.(type) {
+	case *string:
+		*p = v
+	case *int:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", v)
+		}
+		*p = n
+	case *bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", v)
+		}
+		*p = b
+	case *float64:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", v)
+		}
+		*p = f
+	case *[]string:
+		*p = nil
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*p = append(*p, item)
+			}
+		}
+	case *time.Time:
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return fmt.Errorf("%q is not a YYYY-MM-DD date", v)
+		}
+		*p = t
+	default:
+		return fmt.Errorf("unsupported setting type %s", reflect.TypeOf(field))
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+// Validate reports every invalid setting at once. In production it also
+// refuses the development defaults for secrets and an unconfigured SMTP
+// account.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	oneOf := func(name, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s must be one of %s, got %q", name, strings.Join(allowed, ", "), value))
+	}
+
+	check(isPort(c.Server.Port), "server.port must be a port number, got %q", c.Server.Port)
+	check(strings.HasPrefix(c.Server.PublicURL, "http://") || strings.HasPrefix(c.Server.PublicURL, "https://"), "server.public_url must be an http or https URL, got %q", c.Server.PublicURL)
+	oneOf("server.environment", c.Server.Environment, "development", "staging", "production")
+	check(c.Server.ReadTimeoutSeconds > 0, "server.read_timeout_seconds must be positive")
+	check(c.Server.WriteTimeoutSeconds > 0, "server.write_timeout_seconds must be positive")
+	check(c.Server.IdleTimeoutSeconds > 0, "server.idle_timeout_seconds must be positive")
+	check(c.Server.ShutdownTimeoutSeconds > 0, "server.shutdown_timeout_seconds must be positive")
+	oneOf("server.log_level", strings.ToLower(c.Server.LogLevel), "debug", "info", "warn", "error")
+	oneOf("server.log_format", strings.ToLower(c.Server.LogFormat), "json", "text")
+
+	check(c.Database.Host != "", "database.host is required")
+	check(isPort(c.Database.Port), "database.port must be a port number, got %q", c.Database.Port)
+	check(c.Database.DBName != "", "database.name is required")
+	oneOf("database.ssl_mode", c.Database.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	check(c.Database.QueryTimeoutSeconds >= 0, "database.query_timeout_seconds must not be negative")
+	check(c.Database.SlowQueryMillis >= 0, "database.slow_query_millis must not be negative")
+
+	check(c.JWT.Secret != "" || c.JWT.SigningKey != "", "jwt.secret or jwt.signing_key is required")
+	check(c.JWT.Issuer != "", "jwt.issuer is required")
+	check(c.JWT.Audience != "", "jwt.audience is required")
+	for i, key := range c.JWT.Keys {
+		check(key.ID != "" && key.ID != "default", "jwt.keys[%d].id must be set and not \"default\"", i)
+		oneOf(fmt.Sprintf("jwt.keys[%d].algorithm", i), key.Algorithm, "HS256", "RS256", "EdDSA")
+		if key.Algorithm == "HS256" {
+			check(len(key.Secret) >= 32, "jwt.keys[%d].secret must be at least 32 characters", i)
+		} else {
+			check(key.KeyFile != "", "jwt.keys[%d].key_file is required for %s", i, key.Algorithm)
+		}
+	}
+
+	check(isPort(c.Email.SMTPPort), "email.smtp_port must be a port number, got %q", c.Email.SMTPPort)
+	check(strings.Contains(c.Email.FromEmail, "@"), "email.from_email must be an email address, got %q", c.Email.FromEmail)
+
+	check(c.Retention.GracePeriodDays >= 0, "retention.grace_period_days must not be negative")
+	check(c.Retention.IntervalMinutes > 0, "retention.interval_minutes must be positive")
+	oneOf("retention.deleted_user_content", c.Retention.DeletedUserContent, "anonymize", "delete")
+
+	_, err := time.LoadLocation(c.Leaderboard.TimeZone)
+	check(err == nil, "leaderboard.time_zone must be an IANA time zone, got %q", c.Leaderboard.TimeZone)
+	check(len(c.Leaderboard.SemesterStarts) > 0, "leaderboard.semester_starts must list at least one date")
+	for _, s := range c.Leaderboard.SemesterStarts {
+		_, err := time.Parse("01-02", s)
+		check(err == nil, "leaderboard.semester_starts must be MM-DD dates, got %q", s)
+	}
+
+	check((c.Storage.PublicURL == "") == (c.Storage.Dir == ""), "storage.public_url and storage.dir must be set together")
+	check(c.Storage.PublicURL == "" || strings.HasPrefix(c.Storage.PublicURL, "http://") || strings.HasPrefix(c.Storage.PublicURL, "https://"), "storage.public_url must be an http or https URL, got %q", c.Storage.PublicURL)
+
+	oneOf("tracing.exporter", c.Tracing.Exporter, "none", "stdout", "otlp")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+
+	check(c.Health.DrainSeconds >= 0, "health.drain_seconds must not be negative")
+
+	check(c.API.LegacySunset.IsZero() || c.API.LegacySunset.After(c.API.LegacyDeprecated), "api.legacy_sunset must be after api.legacy_deprecated")
+	check(c.API.V1Sunset.IsZero() || c.API.V1Sunset.After(c.API.V1Deprecated), "api.v1_sunset must be after api.v1_deprecated")
+
+	check(len(c.CORS.AllowedOrigins) > 0, "cors.allowed_origins must list at least one origin")
+	for _, origin := range c.CORS.AllowedOrigins {
+		check(origin != "*", "cors.allowed_origins must not contain * because credentials are allowed")
+	}
+	check(c.CORS.MaxAgeSeconds >= 0, "cors.max_age_seconds must not be negative")
+	oneOf("cookie.same_site", c.Cookie.SameSite, "strict", "lax", "none")
+	check(c.Cookie.SameSite != "none" || c.Cookie.Secure, "cookie.secure is required when cookie.same_site is none")
+	check(c.Security.HSTSMaxAgeSeconds >= 0, "security.hsts_max_age_seconds must not be negative")
+	check(!strings.Contains(c.Security.CSP, "frame-ancestors"), "security.csp must not set frame-ancestors; use security.frame_ancestors")
+
+	if c.Server.Environment == "production" {
+		if c.JWT.Secret != "" {
+			check(c.JWT.Secret != DefaultJWTSecret, "jwt.secret must be changed from the default in production")
+			check(len(c.JWT.Secret) >= 32, "jwt.secret must be at least 32 characters in production")
+		}
+		check(c.Database.Password != "" && c.Database.Password != "postgres", "database.password must be changed from the default in production")
+		check(c.Email.SMTPUser != "", "email.smtp_user is required in production")
+		check(c.Email.SMTPPassword != "", "email.smtp_password is required in production")
+		check(c.Cookie.Secure, "cookie.secure is required in production")
+		check(strings.HasPrefix(c.Server.PublicURL, "https://"), "server.public_url must be https in production, got %q", c.Server.PublicURL)
+		for _, origin := range c.CORS.AllowedOrigins {
+			check(strings.HasPrefix(origin, "https://"), "cors.allowed_origins must be https in production, got %q", origin)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func isPort(s string) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n > 0 && n < 65536
+}
+
+// Redacted returns a copy of c with every secret that is set replaced by a
+// placeholder, for printing.
+func (c *Config) Redacted() *Config {
+	out := *c
+	for _, secret := range []*string{&out.Database.Password, &out.JWT.Secret, &out.Email.SMTPPassword, &out.Metrics.Token} {
+		if *secret != "" {
+			*secret = redacted
+		}
+	}
+	out.JWT.Keys = append([]JWTKey(nil), c.JWT.Keys...)
+	for i := range out.JWT.Keys {
+		if out.JWT.Keys[i].Secret != "" {
+			out.JWT.Keys[i].Secret = redacted
+		}
+	}
+	return &out
+}
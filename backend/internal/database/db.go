@@ -3,13 +3,13 @@ package database
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/ISKOnnect/iskonnect-web/internal/config"
 	_ "github.com/lib/pq"
 )
 
-func Connect(cfg config.DatabaseConfig) (*sql.DB, error) {
+func Connect(cfg config.DatabaseConfig, logger *slog.Logger) (*sql.DB, error) {
 	connStr := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode,
@@ -26,6 +26,6 @@ func Connect(cfg config.DatabaseConfig) (*sql.DB, error) {
 
 	db.SetMaxOpenConns(25)
 	db.SetMaxIdleConns(25)
-	log.Printf("Connected to database: %s", cfg.DBName)
+	logger.Info("connected to database", "dbname", cfg.DBName)
 	return db, nil
 }
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/*.up.sql
+var migrationFiles embed.FS
+
+// migrationLockID keys the advisory lock that keeps two instances starting
+// at once from applying the same migration twice.
+const migrationLockID = 4_815_162_342
+
+type migration struct {
+	version int
+	name    string
+}
+
+// migrations lists the embedded up migrations in version order.
+func migrations() []migration {
+	entries, _ := migrationFiles.ReadDir("migrations")
+	var list []migration
+	for _, e := range entries {
+		prefix, _, _ := strings.Cut(path.Base(e.Name()), "_")
+		if v, err := strconv.Atoi(prefix); err == nil {
+			list = append(list, migration{version: v, name: e.Name()})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].version < list[j].version })
+	return list
+}
+
+// ExpectedMigrationVersion is the highest migration shipped with this build.
+func ExpectedMigrationVersion() int {
+	list := migrations()
+	if len(list) == 0 {
+		return 0
+	}
+	return list[len(list)-1].version
+}
+
+// MigrationVersion reads the version Migrate recorded in schema_migrations
+// and whether the last migration failed part way.
+func MigrationVersion(ctx context.Context, db *sql.DB) (version int, dirty bool, err error) {
+	err = db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	return version, dirty, err
+}
+
+// Migrate applies every embedded migration newer than the recorded version,
+// each in its own transaction together with the new version. The
+// schema_migrations table has golang-migrate's layout, so databases it
+// migrated carry on from where it left off; a version it left dirty must be
+// fixed by hand.
+func Migrate(ctx context.Context, db *sql.DB, logger *slog.Logger) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("locking migrations: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	if _, err := conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)"); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+	var current int
+	var dirty bool
+	err = conn.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&current, &dirty)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("migration %d is dirty", current)
+	}
+
+	for _, m := range migrations() {
+		if m.version <= current {
+			continue
+		}
+		if err := apply(ctx, conn, m); err != nil {
+			return fmt.Errorf("migration %s: %w", m.name, err)
+		}
+		logger.Info("applied migration", "version", m.version, "name", m.name)
+	}
+	return nil
+}
+
+func apply(ctx context.Context, conn *sql.Conn, m migration) error {
+	body, err := migrationFiles.ReadFile(path.Join("migrations", m.name))
+	if err != nil {
+		return err
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, string(body)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)", m.version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS point_events;
+DROP INDEX IF EXISTS idx_users_college_course;
+ALTER TABLE users DROP COLUMN IF EXISTS course;
+ALTER TABLE users DROP COLUMN IF EXISTS college;
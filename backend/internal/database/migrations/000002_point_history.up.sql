@@ -0,0 +1,20 @@
+ALTER TABLE users ADD COLUMN college VARCHAR(50);
+ALTER TABLE users ADD COLUMN course VARCHAR(50);
+
+CREATE INDEX idx_users_college_course ON users(college, course);
+
+CREATE TABLE point_events (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    points INTEGER NOT NULL,
+    reason VARCHAR(50) NOT NULL,
+    material_id INTEGER REFERENCES materials(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_point_events_user_id ON point_events(user_id);
+CREATE INDEX idx_point_events_created_at ON point_events(created_at);
+
+-- Carry lifetime points over so the all-time board matches users.points.
+INSERT INTO point_events (user_id, points, reason, created_at)
+SELECT id, points, 'backfill', updated_at FROM users WHERE points <> 0;
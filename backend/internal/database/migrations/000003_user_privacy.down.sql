@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS hide_activity;
+ALTER TABLE users DROP COLUMN IF EXISTS hide_real_name;
+ALTER TABLE users DROP COLUMN IF EXISTS handle;
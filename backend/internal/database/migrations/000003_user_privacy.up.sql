@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN handle VARCHAR(30) UNIQUE;
+ALTER TABLE users ADD COLUMN hide_real_name BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN hide_activity BOOLEAN NOT NULL DEFAULT FALSE;
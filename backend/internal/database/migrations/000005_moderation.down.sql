@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS material_reports;
+ALTER TABLE materials DROP COLUMN IF EXISTS hidden_at;
+ALTER TABLE users DROP COLUMN IF EXISTS is_moderator;
@@ -0,0 +1,21 @@
+ALTER TABLE users ADD COLUMN is_moderator BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE materials ADD COLUMN hidden_at TIMESTAMP;
+
+CREATE TABLE material_reports (
+    id SERIAL PRIMARY KEY,
+    material_id INTEGER REFERENCES materials(id) ON DELETE SET NULL,
+    reporter_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason VARCHAR(30) NOT NULL CHECK (reason IN ('copyright', 'wrong_subject', 'spam', 'inappropriate', 'other')),
+    details TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'reviewing', 'resolved', 'dismissed')),
+    resolution VARCHAR(20),
+    handled_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    handled_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_material_reports_status ON material_reports(status, created_at);
+CREATE INDEX idx_material_reports_material_id ON material_reports(material_id);
+CREATE UNIQUE INDEX idx_material_reports_pending ON material_reports(material_id, reporter_id)
+    WHERE status IN ('open', 'reviewing');
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS users_email_sync ON users;
+DROP FUNCTION IF EXISTS users_sync_credentials_email();
+ALTER TABLE email_verifications DROP COLUMN IF EXISTS new_email;
+ALTER TABLE users DROP COLUMN IF EXISTS session_version;
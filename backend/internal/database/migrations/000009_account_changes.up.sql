@@ -0,0 +1,25 @@
+-- Every JWT carries the session_version it was issued under. Bumping it
+-- revokes all of the user's outstanding tokens.
+ALTER TABLE users ADD COLUMN session_version INTEGER NOT NULL DEFAULT 0;
+
+-- A row with new_email is a pending address change rather than a
+-- registration verification.
+ALTER TABLE email_verifications ADD COLUMN new_email VARCHAR(100);
+
+-- user_credentials.email duplicates users.email. Repair any drift, then keep
+-- the copy in step with every update to users.
+UPDATE user_credentials c SET email = u.email
+FROM users u
+WHERE u.id = c.id AND c.email <> u.email;
+
+CREATE FUNCTION users_sync_credentials_email() RETURNS trigger AS $$
+BEGIN
+    UPDATE user_credentials SET email = NEW.email WHERE id = NEW.id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_email_sync
+    AFTER UPDATE OF email ON users
+    FOR EACH ROW WHEN (OLD.email IS DISTINCT FROM NEW.email)
+    EXECUTE FUNCTION users_sync_credentials_email();
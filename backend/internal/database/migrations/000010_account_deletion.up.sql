@@ -0,0 +1,3 @@
+-- Set when the retention job scrubbed a purged user instead of deleting the
+-- row, so their materials and comments stay attributed to a placeholder.
+ALTER TABLE users ADD COLUMN anonymized_at TIMESTAMP;
@@ -0,0 +1,31 @@
+-- Every single-use token mailed to a user. purpose keeps a value issued for
+-- one flow from being accepted by another, and only the SHA-256 of the value
+-- is stored. Consumed or superseded rows keep consumed_at until the
+-- retention job clears them.
+CREATE TABLE user_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('email_verify', 'email_change', 'password_otp', 'password_reset')),
+    token_hash CHAR(64) NOT NULL,
+    new_email VARCHAR(100),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    consumed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_tokens_hash ON user_tokens(purpose, token_hash);
+CREATE INDEX idx_user_tokens_user_id ON user_tokens(user_id, purpose);
+
+-- Carry over pending email verifications and changes. Reset codes only live
+-- for fifteen minutes and are dropped; users can request another.
+INSERT INTO user_tokens (user_id, purpose, token_hash, new_email, expires_at, created_at)
+SELECT user_id,
+       CASE WHEN new_email IS NULL THEN 'email_verify' ELSE 'email_change' END,
+       encode(sha256(convert_to(token, 'UTF8')), 'hex'),
+       new_email, expires_at, created_at
+FROM email_verifications
+WHERE expires_at > NOW();
+
+DROP TABLE email_verifications;
+DROP TABLE reset_tokens;
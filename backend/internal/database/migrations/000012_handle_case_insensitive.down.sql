@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS users_handle_lower_key;
+ALTER TABLE users ADD CONSTRAINT users_handle_key UNIQUE (handle);
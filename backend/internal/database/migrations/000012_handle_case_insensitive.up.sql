@@ -0,0 +1,11 @@
+-- Handles are compared case-insensitively everywhere, but the original
+-- UNIQUE constraint was case-sensitive. Rename any later duplicates, which
+-- can only have come from a race, then enforce uniqueness on LOWER(handle).
+UPDATE users u SET handle = LEFT(u.handle, 29 - LENGTH(u.id::text)) || '_' || u.id
+WHERE EXISTS (
+    SELECT 1 FROM users o
+    WHERE LOWER(o.handle) = LOWER(u.handle) AND o.id < u.id
+);
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_handle_key;
+CREATE UNIQUE INDEX users_handle_lower_key ON users (LOWER(handle));
@@ -0,0 +1,2 @@
+-- The redacted values cannot be restored.
+SELECT 1;
@@ -0,0 +1,18 @@
+-- Earlier audit diffs recorded names, email addresses and student numbers.
+-- Keep the record that those fields changed but drop their values. The
+-- append-only trigger is lifted for this one rewrite only.
+ALTER TABLE audit_events DISABLE TRIGGER audit_events_no_update;
+
+UPDATE audit_events SET diff = (
+    SELECT jsonb_object_agg(key, CASE
+        WHEN key IN ('email', 'student_number', 'first_name', 'last_name', 'handle')
+        THEN jsonb_build_object(
+            'before', CASE WHEN value->'before' = 'null'::jsonb THEN NULL ELSE '"[REDACTED]"'::jsonb END,
+            'after', CASE WHEN value->'after' = 'null'::jsonb THEN NULL ELSE '"[REDACTED]"'::jsonb END)
+        ELSE value
+    END)
+    FROM jsonb_each(diff)
+)
+WHERE diff ?| ARRAY['email', 'student_number', 'first_name', 'last_name', 'handle'];
+
+ALTER TABLE audit_events ENABLE TRIGGER audit_events_no_update;
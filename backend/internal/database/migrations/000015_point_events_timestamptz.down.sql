@@ -0,0 +1 @@
+ALTER TABLE point_events ALTER COLUMN created_at TYPE TIMESTAMP USING created_at::timestamp;
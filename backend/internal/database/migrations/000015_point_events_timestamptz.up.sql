@@ -0,0 +1,5 @@
+-- Leaderboard windows compare created_at with a timestamptz boundary, so a
+-- plain TIMESTAMP shifted the week, month and semester windows with the
+-- session time zone. Existing rows are read in the session's zone, the
+-- same one NOW() wrote them in.
+ALTER TABLE point_events ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at::timestamptz;
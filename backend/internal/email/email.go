@@ -2,46 +2,96 @@ package email
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"html/template"
+	"mime"
 	"net/smtp"
-	"text/template"
+	"net/url"
+	"strings"
 
 	"github.com/ISKOnnect/iskonnect-web/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/ISKOnnect/iskonnect-web/internal/email")
+
+// headerBreaks flattens line breaks out of user-supplied text, such as a
+// material title, before it goes into a header.
+var headerBreaks = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
 type Sender struct {
-	cfg config.EmailConfig
+	cfg       config.EmailConfig
+	publicURL string
+	onSend    func(kind string, err error)
 }
 
-func NewSender(cfg config.EmailConfig) *Sender {
-	return &Sender{cfg: cfg}
+// NewSender returns a Sender whose links start with publicURL. onSend, if
+// not nil, is called after every send attempt with the email kind and the
+// SMTP error.
+func NewSender(cfg config.EmailConfig, publicURL string, onSend func(kind string, err error)) *Sender {
+	return &Sender{cfg: cfg, publicURL: strings.TrimSuffix(publicURL, "/"), onSend: onSend}
 }
 
-func (s *Sender) SendVerificationEmail(to, token string) error {
+// link returns the public URL of the API path with token as its query.
+func (s *Sender) link(path, token string) string {
+	return s.publicURL + path + "?token=" + url.QueryEscape(token)
+}
+
+func (s *Sender) SendVerificationEmail(ctx context.Context, to, token string) error {
 	subject := "Verify Your ISKOnnect Account"
-	link := fmt.Sprintf("http://localhost:8080/api/auth/verify-email?token=%s", token)
-	body, err := s.parseTemplate("verification", map[string]string{"Link": link})
+	body, err := s.parseTemplate("verification", map[string]string{"Link": s.link("/api/auth/verify-email", token)})
 	if err != nil {
 		return err
 	}
-	return s.send(to, subject, body)
+	return s.send(ctx, "verification", to, subject, body)
 }
 
-func (s *Sender) SendPasswordResetEmail(to, otp string) error {
+func (s *Sender) SendPasswordResetEmail(ctx context.Context, to, otp string) error {
 	subject := "Reset Your ISKOnnect Password"
 	body, err := s.parseTemplate("reset", map[string]string{"OTP": otp})
 	if err != nil {
 		return err
 	}
-	return s.send(to, subject, body)
+	return s.send(ctx, "reset", to, subject, body)
+}
+
+func (s *Sender) SendEmailChangeEmail(ctx context.Context, to, token string) error {
+	subject := "Confirm Your New ISKOnnect Email"
+	body, err := s.parseTemplate("email_change", map[string]string{"Link": s.link("/api/auth/confirm-email", token)})
+	if err != nil {
+		return err
+	}
+	return s.send(ctx, "email_change", to, subject, body)
+}
+
+func (s *Sender) SendCommentNotification(ctx context.Context, to, heading, materialTitle, author, comment string) error {
+	subject := fmt.Sprintf("%s on \"%s\"", heading, materialTitle)
+	body, err := s.parseTemplate("comment", map[string]string{
+		"Heading": heading,
+		"Title":   materialTitle,
+		"Author":  author,
+		"Comment": comment,
+	})
+	if err != nil {
+		return err
+	}
+	return s.send(ctx, "comment", to, subject, body)
 }
 
-func (s *Sender) send(to, subject, body string) error {
+func (s *Sender) send(ctx context.Context, kind, to, subject, body string) error {
+	_, span := tracer.Start(ctx, "smtp.send", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("email.kind", kind)))
+	defer span.End()
+
 	auth := smtp.PlainAuth("", s.cfg.SMTPUser, s.cfg.SMTPPassword, s.cfg.SMTPHost)
 	headers := map[string]string{
 		"From":         fmt.Sprintf("%s <%s>", s.cfg.FromName, s.cfg.FromEmail),
 		"To":           to,
-		"Subject":      subject,
+		"Subject":      mime.QEncoding.Encode("UTF-8", headerBreaks.Replace(subject)),
 		"MIME-Version": "1.0",
 		"Content-Type": "text/html; charset=UTF-8",
 	}
@@ -53,7 +103,15 @@ func (s *Sender) send(to, subject, body string) error {
 	msg += "\r\n" + body
 
 	addr := fmt.Sprintf("%s:%s", s.cfg.SMTPHost, s.cfg.SMTPPort)
-	return smtp.SendMail(addr, auth, s.cfg.FromEmail, []string{to}, []byte(msg))
+	err := smtp.SendMail(addr, auth, s.cfg.FromEmail, []string{to}, []byte(msg))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "send failed")
+	}
+	if s.onSend != nil {
+		s.onSend(kind, err)
+	}
+	return err
 }
 
 func (s *Sender) parseTemplate(name string, data map[string]string) (string, error) {
@@ -88,6 +146,36 @@ func (s *Sender) parseTemplate(name string, data map[string]string) (string, err
 			</body>
 			</html>
 		`,
+		"email_change": `
+			<!DOCTYPE html>
+			<html>
+			<body style="font-family: Arial; max-width: 600px; margin: 20px auto;">
+				<div style="background: #A31D1D; color: white; padding: 20px; text-align: center;">
+					<h1>Confirm Your New Email</h1>
+				</div>
+				<div style="padding: 20px; background: #f9f9f9;">
+					<p>Confirm this address for your ISKOnnect account by clicking below:</p>
+					<a href="{{.Link}}" style="display: block; background: #A31D1D; color: white; padding: 10px; text-align: center; text-decoration: none;">Confirm Email</a>
+					<p>Or use this link: {{.Link}}</p>
+					<p>Expires in 24 hours. If you didn't ask for this, ignore this email.</p>
+				</div>
+			</body>
+			</html>
+		`,
+		"comment": `
+			<!DOCTYPE html>
+			<html>
+			<body style="font-family: Arial; max-width: 600px; margin: 20px auto;">
+				<div style="background: #A31D1D; color: white; padding: 20px; text-align: center;">
+					<h1>{{.Heading}}</h1>
+				</div>
+				<div style="padding: 20px; background: #f9f9f9;">
+					<p><strong>{{.Author}}</strong> wrote on <strong>{{.Title}}</strong>:</p>
+					<blockquote style="border-left: 4px solid #A31D1D; margin: 0; padding-left: 10px;">{{.Comment}}</blockquote>
+				</div>
+			</body>
+			</html>
+		`,
 	}
 
 	tmpl, err := template.New(name).Parse(templates[name])
@@ -99,4 +187,4 @@ func (s *Sender) parseTemplate(name string, data map[string]string) (string, err
 		return "", err
 	}
 	return buf.String(), nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,37 @@
+package email
+
+import (
+	"context"
+	"sync"
+)
+
+// Outbox runs sends in the background, so a request doesn't wait on SMTP,
+// and lets shutdown wait for the ones still in flight. The zero value is
+// ready to use.
+type Outbox struct {
+	wg sync.WaitGroup
+}
+
+// Go runs send in the background.
+func (o *Outbox) Go(send func()) {
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+		send()
+	}()
+}
+
+// Wait blocks until every send started by Go has finished or ctx is done.
+func (o *Outbox) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		o.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
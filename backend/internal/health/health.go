@@ -0,0 +1,112 @@
+// Package health serves the liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/api/render"
+	"github.com/ISKOnnect/iskonnect-web/internal/config"
+	"github.com/ISKOnnect/iskonnect-web/internal/database"
+)
+
+const checkTimeout = 2 * time.Second
+
+type Checker struct {
+	db        *sql.DB
+	smtpAddr  string
+	checkSMTP bool
+	draining  atomic.Bool
+}
+
+func NewChecker(db *sql.DB, cfg *config.Config) *Checker {
+	return &Checker{
+		db:        db,
+		smtpAddr:  net.JoinHostPort(cfg.Email.SMTPHost, cfg.Email.SMTPPort),
+		checkSMTP: cfg.Health.CheckSMTP,
+	}
+}
+
+// Drain makes readiness fail from now on so load balancers stop routing
+// traffic here before the server shuts down.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+type checkResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Liveness reports that the process is up. It checks no dependencies, so
+// an outage of Postgres or SMTP never gets the pod restarted.
+func (c *Checker) Liveness(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, http.StatusOK, checkResult{Status: "ok"})
+}
+
+// Readiness checks the database, the schema version and, when enabled, that
+// the SMTP server accepts connections. It responds 503 if any check fails
+// or the server is draining.
+func (c *Checker) Readiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	checks := map[string]checkResult{
+		"database":   result(c.db.PingContext(ctx)),
+		"migrations": result(c.checkMigrations(ctx)),
+	}
+	if c.checkSMTP {
+		checks["smtp"] = result(c.checkSMTPReachable(ctx))
+	}
+
+	status := http.StatusOK
+	overall := "ok"
+	for _, check := range checks {
+		if check.Status != "ok" {
+			status, overall = http.StatusServiceUnavailable, "unavailable"
+		}
+	}
+	if c.draining.Load() {
+		status, overall = http.StatusServiceUnavailable, "draining"
+	}
+
+	render.JSON(w, status, map[string]interface{}{
+		"status": overall,
+		"checks": checks,
+	})
+}
+
+func (c *Checker) checkMigrations(ctx context.Context) error {
+	version, dirty, err := database.MigrationVersion(ctx, c.db)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("migration %d is dirty", version)
+	}
+	if expected := database.ExpectedMigrationVersion(); version != expected {
+		return fmt.Errorf("schema at version %d, expected %d", version, expected)
+	}
+	return nil
+}
+
+func (c *Checker) checkSMTPReachable(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.smtpAddr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func result(err error) checkResult {
+	if err != nil {
+		return checkResult{Status: "error", Error: err.Error()}
+	}
+	return checkResult{Status: "ok"}
+}
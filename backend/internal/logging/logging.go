@@ -0,0 +1,165 @@
+// Package logging builds the application's slog logger. Every record logged
+// with a request context carries the chi request ID and the authenticated
+// user ID, and attributes that look like secrets are redacted.
+//
+// Redaction goes by attribute key alone; values are never inspected. A
+// secret in the message, in an error string, inside a struct or map logged
+// as one attribute, or under an innocuous key such as "url" is written as
+// is. Log secrets under their own key, or not at all.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/config"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const redacted = "[REDACTED]"
+
+// secretKeys are attribute keys whose values are never written. Keys ending
+// in _token, _password or _secret are redacted as well.
+var secretKeys = map[string]bool{
+	"password":      true,
+	"token":         true,
+	"otp":           true,
+	"secret":        true,
+	"authorization": true,
+	"cookie":        true,
+	"set-cookie":    true,
+}
+
+// New returns a logger writing to w in the format and at the level set by
+// LOG_FORMAT (json or text) and LOG_LEVEL (debug, info, warn, error).
+func New(cfg config.ServerConfig, w io.Writer) *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		level = slog.LevelInfo
+	}
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redact}
+
+	var h slog.Handler
+	if strings.EqualFold(cfg.LogFormat, "text") {
+		h = slog.NewTextHandler(w, opts)
+	} else {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(contextHandler{h})
+}
+
+func isSecret(key string) bool {
+	key = strings.ToLower(key)
+	return secretKeys[key] ||
+		strings.HasSuffix(key, "_token") ||
+		strings.HasSuffix(key, "_password") ||
+		strings.HasSuffix(key, "_secret")
+}
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if isSecret(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+	return a
+}
+
+// contextHandler adds request_id, user_id and trace_id from the record's
+// context.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := middleware.GetReqID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if id := userID(ctx); id != 0 {
+		r.AddAttrs(slog.Int("user_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+type stateKey struct{}
+
+// requestState is shared by every context derived from a request, so the
+// access log written by an outer middleware sees the user authenticated by
+// an inner one.
+type requestState struct {
+	userID int
+}
+
+// SetUserID records the authenticated user for the request's log lines.
+func SetUserID(ctx context.Context, id int) {
+	if s, ok := ctx.Value(stateKey{}).(*requestState); ok {
+		s.userID = id
+	}
+}
+
+func userID(ctx context.Context) int {
+	if id, ok := ctx.Value("user_id").(int); ok {
+		return id
+	}
+	if s, ok := ctx.Value(stateKey{}).(*requestState); ok {
+		return s.userID
+	}
+	return 0
+}
+
+// Middleware writes one access log line per request. Only the path is
+// logged; query strings can carry verification and reset tokens.
+func Middleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), stateKey{}, &requestState{})
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				level := slog.LevelInfo
+				if status >= http.StatusInternalServerError {
+					level = slog.LevelError
+				}
+				logger.LogAttrs(ctx, level, "request",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Int("status", status),
+					slog.Int("bytes", ww.BytesWritten()),
+					slog.Duration("duration", time.Since(start)),
+					slog.String("remote_ip", ClientIP(r)),
+				)
+			}()
+			next.ServeHTTP(ww, r.WithContext(ctx))
+		})
+	}
+}
+
+// ClientIP returns the address of the client behind r, without the port.
+// The router runs chi's RealIP first, so behind the proxy this is the
+// address it forwarded rather than the proxy's own.
+func ClientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
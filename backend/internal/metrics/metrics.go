@@ -0,0 +1,170 @@
+// Package metrics exposes Prometheus metrics for HTTP traffic, the database
+// pool, outgoing email and domain events. All methods are safe to call on a
+// nil *Metrics, which records nothing.
+package metrics
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "iskonnect"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requestDuration *prometheus.HistogramVec
+	emailsSent      *prometheus.CounterVec
+	registrations   prometheus.Counter
+	logins          *prometheus.CounterVec
+	uploads         prometheus.Counter
+	votes           *prometheus.CounterVec
+}
+
+// New registers the application metrics along with Go runtime, process and
+// sql.DB pool collectors for db.
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route pattern, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		emailsSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "emails_sent_total",
+			Help:      "Emails sent by kind and result (success or failure).",
+		}, []string{"kind", "result"}),
+		registrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "registrations_total",
+			Help:      "Accounts registered.",
+		}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Login attempts by result (success or failure).",
+		}, []string{"result"}),
+		uploads: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "uploads_total",
+			Help:      "Materials uploaded.",
+		}),
+		votes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "votes_total",
+			Help:      "Votes cast by type.",
+		}, []string{"type"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, "iskonnect"),
+		m.requestDuration,
+		m.emailsSent,
+		m.registrations,
+		m.logins,
+		m.uploads,
+		m.votes,
+	)
+	return m
+}
+
+// Middleware observes request latency. The route label is the matched chi
+// pattern, such as /api/materials/{id}, so IDs don't explode cardinality;
+// unmatched requests are labeled "unmatched".
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				route = pattern
+			}
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		m.requestDuration.WithLabelValues(r.Method, route, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Handler serves the registry in the Prometheus exposition format. When
+// token is set, requests must carry it as a bearer token.
+func (m *Metrics) Handler(token string) http.Handler {
+	h := promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// EmailSent counts one email send attempt of the given kind.
+func (m *Metrics) EmailSent(kind string, err error) {
+	if m == nil {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.emailsSent.WithLabelValues(kind, result).Inc()
+}
+
+func (m *Metrics) Registered() {
+	if m == nil {
+		return
+	}
+	m.registrations.Inc()
+}
+
+func (m *Metrics) LoginAttempted(succeeded bool) {
+	if m == nil {
+		return
+	}
+	label := "failure"
+	if succeeded {
+		label = "success"
+	}
+	m.logins.WithLabelValues(label).Inc()
+}
+
+func (m *Metrics) Uploaded() {
+	if m == nil {
+		return
+	}
+	m.uploads.Inc()
+}
+
+func (m *Metrics) Voted(voteType string) {
+	if m == nil {
+		return
+	}
+	m.votes.WithLabelValues(strings.ToLower(voteType)).Inc()
+}
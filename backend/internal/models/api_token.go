@@ -0,0 +1,139 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APITokenPrefix starts every personal access token, so they can be told
+// apart from JWTs and spotted by secret scanners.
+const APITokenPrefix = "isk_pat_"
+
+// Scopes a personal access token can be granted.
+const (
+	ScopeMaterialsRead   = "materials:read"
+	ScopeMaterialsUpload = "materials:upload"
+	ScopeMaterialsVote   = "materials:vote"
+)
+
+var Scopes = []string{ScopeMaterialsRead, ScopeMaterialsUpload, ScopeMaterialsVote}
+
+type APIToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TokenGrant is what an accepted personal access token authenticates as.
+type TokenGrant struct {
+	TokenID     int
+	UserID      int
+	IsStudent   bool
+	IsModerator bool
+	Scopes      []string
+}
+
+type APITokenModel struct {
+	db   *sql.DB
+	opts QueryOptions
+}
+
+func NewAPITokenModel(db *sql.DB, opts QueryOptions) *APITokenModel {
+	return &APITokenModel{db: db, opts: opts}
+}
+
+// Create stores t with the hash of its secret and fills in ID and CreatedAt.
+func (m *APITokenModel) Create(ctx context.Context, t *APIToken, hash string) error {
+	ctx, done := m.opts.begin(ctx, "APITokenModel.Create")
+	defer done()
+	query := `
+		INSERT INTO api_tokens (user_id, name, prefix, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	return m.db.QueryRowContext(ctx, query, t.UserID, t.Name, t.Prefix, hash, pq.Array(t.Scopes), t.ExpiresAt).Scan(&t.ID, &t.CreatedAt)
+}
+
+// ListByUser returns the user's tokens, expired ones included, newest first.
+func (m *APITokenModel) ListByUser(ctx context.Context, userID int) ([]*APIToken, error) {
+	ctx, done := m.opts.begin(ctx, "APITokenModel.ListByUser")
+	defer done()
+	query := `
+		SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at
+		FROM api_tokens WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+	rows, err := m.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*APIToken{}
+	for rows.Next() {
+		var t APIToken
+		var scopes pq.StringArray
+		var lastUsed sql.NullTime
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &scopes, &t.ExpiresAt, &lastUsed, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		t.Scopes = scopes
+		if lastUsed.Valid {
+			t.LastUsedAt = &lastUsed.Time
+		}
+		tokens = append(tokens, &t)
+	}
+	return tokens, rows.Err()
+}
+
+func (m *APITokenModel) CountByUser(ctx context.Context, userID int) (int, error) {
+	ctx, done := m.opts.begin(ctx, "APITokenModel.CountByUser")
+	defer done()
+	var n int
+	err := m.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM api_tokens WHERE user_id = $1`, userID).Scan(&n)
+	return n, err
+}
+
+// Delete revokes one of the user's tokens. It returns sql.ErrNoRows if the
+// user has no token with that ID.
+func (m *APITokenModel) Delete(ctx context.Context, userID, id int) error {
+	ctx, done := m.opts.begin(ctx, "APITokenModel.Delete")
+	defer done()
+	res, err := m.db.ExecContext(ctx, `DELETE FROM api_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Authenticate looks up an unexpired token by hash, records that it was
+// used and returns what it grants. Tokens of deleted users are not found.
+func (m *APITokenModel) Authenticate(ctx context.Context, hash string) (*TokenGrant, error) {
+	ctx, done := m.opts.begin(ctx, "APITokenModel.Authenticate")
+	defer done()
+	query := `
+		UPDATE api_tokens t SET last_used_at = NOW()
+		FROM users u
+		WHERE t.token_hash = $1 AND t.expires_at > NOW()
+		  AND u.id = t.user_id AND u.deleted_at IS NULL
+		RETURNING t.id, t.user_id, u.is_student, u.is_moderator, t.scopes
+	`
+	var g TokenGrant
+	var scopes pq.StringArray
+	if err := m.db.QueryRowContext(ctx, query, hash).Scan(&g.TokenID, &g.UserID, &g.IsStudent, &g.IsModerator, &scopes); err != nil {
+		return nil, err
+	}
+	g.Scopes = scopes
+	return &g, nil
+}
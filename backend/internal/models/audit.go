@@ -0,0 +1,135 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+type AuditEvent struct {
+	ID         int64           `json:"id"`
+	ActorID    *int            `json:"actor_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   *int            `json:"target_id"`
+	RequestID  string          `json:"request_id"`
+	IP         string          `json:"ip"`
+	Diff       json.RawMessage `json:"diff"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type AuditFilter struct {
+	ActorID    *int
+	Action     string
+	TargetType string
+	TargetID   *int
+	Since      *time.Time
+	Until      *time.Time
+	Limit      int
+	Offset     int
+}
+
+type AuditModel struct {
+	db   *sql.DB
+	opts QueryOptions
+}
+
+func NewAuditModel(db *sql.DB, opts QueryOptions) *AuditModel {
+	return &AuditModel{db: db, opts: opts}
+}
+
+func (m *AuditModel) Create(ctx context.Context, e *AuditEvent) error {
+	ctx, done := m.opts.begin(ctx, "AuditModel.Create")
+	defer done()
+	if len(e.Diff) == 0 {
+		e.Diff = json.RawMessage("{}")
+	}
+	query := `
+		INSERT INTO audit_events (actor_id, action, target_type, target_id, request_id, ip, diff, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+	e.CreatedAt = time.Now()
+	return m.db.QueryRowContext(ctx, query, e.ActorID, e.Action, e.TargetType, e.TargetID, e.RequestID, e.IP, []byte(e.Diff), e.CreatedAt).Scan(&e.ID)
+}
+
+const auditFilterClause = `
+	WHERE ($1::int IS NULL OR actor_id = $1)
+	AND ($2::text = '' OR action = $2)
+	AND ($3::text = '' OR target_type = $3)
+	AND ($4::int IS NULL OR target_id = $4)
+	AND ($5::timestamp IS NULL OR created_at >= $5)
+	AND ($6::timestamp IS NULL OR created_at < $6)
+`
+
+func (f AuditFilter) args() []interface{} {
+	return []interface{}{f.ActorID, f.Action, f.TargetType, f.TargetID, f.Since, f.Until}
+}
+
+// List returns one page of matching events, newest first, and the total
+// number of matches.
+func (m *AuditModel) List(ctx context.Context, filter AuditFilter) ([]*AuditEvent, int, error) {
+	ctx, done := m.opts.begin(ctx, "AuditModel.List")
+	defer done()
+	var total int
+	if err := m.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM audit_events"+auditFilterClause, filter.args()...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	events := []*AuditEvent{}
+	query := `
+		SELECT id, actor_id, action, target_type, target_id, request_id, ip, diff, created_at
+		FROM audit_events` + auditFilterClause + `
+		ORDER BY id DESC
+		LIMIT $7 OFFSET $8
+	`
+	err := m.each(ctx, query, append(filter.args(), filter.Limit, filter.Offset), func(e *AuditEvent) error {
+		events = append(events, e)
+		return nil
+	})
+	return events, total, err
+}
+
+// Each streams every matching event, oldest first, to fn without loading
+// them all into memory. Limit and Offset are ignored.
+func (m *AuditModel) Each(ctx context.Context, filter AuditFilter, fn func(*AuditEvent) error) error {
+	ctx, done := m.opts.begin(ctx, "AuditModel.Each")
+	defer done()
+	query := `
+		SELECT id, actor_id, action, target_type, target_id, request_id, ip, diff, created_at
+		FROM audit_events` + auditFilterClause + `
+		ORDER BY id
+	`
+	return m.each(ctx, query, filter.args(), fn)
+}
+
+func (m *AuditModel) each(ctx context.Context, query string, args []interface{}, fn func(*AuditEvent) error) error {
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e AuditEvent
+		var actorID, targetID sql.NullInt64
+		var diff []byte
+		if err := rows.Scan(&e.ID, &actorID, &e.Action, &e.TargetType, &targetID, &e.RequestID, &e.IP, &diff, &e.CreatedAt); err != nil {
+			return err
+		}
+		if actorID.Valid {
+			id := int(actorID.Int64)
+			e.ActorID = &id
+		}
+		if targetID.Valid {
+			id := int(targetID.Int64)
+			e.TargetID = &id
+		}
+		e.Diff = diff
+		if err := fn(&e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
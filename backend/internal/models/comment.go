@@ -0,0 +1,224 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type Comment struct {
+	ID         int              `json:"id"`
+	MaterialID int              `json:"material_id"`
+	ParentID   *int             `json:"parent_id"`
+	AuthorID   int              `json:"-"`
+	Author     *UploaderSummary `json:"author"`
+	Body       string           `json:"body"`
+	MentionIDs []int            `json:"mention_ids"`
+	ReplyCount int              `json:"reply_count"`
+	Edited     bool             `json:"edited"`
+	Deleted    bool             `json:"deleted"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+}
+
+type CommentEdit struct {
+	Body     string    `json:"body"`
+	EditedAt time.Time `json:"edited_at"`
+}
+
+type CommentModel struct {
+	db   *sql.DB
+	opts QueryOptions
+}
+
+func NewCommentModel(db *sql.DB, opts QueryOptions) *CommentModel {
+	return &CommentModel{db: db, opts: opts}
+}
+
+// commentColumns selects a comment with its author, mentions and reply
+// count. Deleted comments keep their place in the thread but lose their body.
+const commentColumns = `
+	SELECT c.id, c.material_id, c.parent_id, c.user_id,
+	       CASE WHEN c.deleted_at IS NULL THEN c.body ELSE '' END,
+	       ARRAY(SELECT cm.user_id FROM comment_mentions cm WHERE cm.comment_id = c.id ORDER BY cm.user_id),
+	       (SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id),
+	       c.edited_at IS NOT NULL, c.deleted_at IS NOT NULL, c.created_at, c.updated_at,
+	       u.first_name, u.last_name, COALESCE(u.handle, ''), u.hide_real_name
+	FROM comments c
+	JOIN users u ON u.id = c.user_id
+`
+
+func scanComment(row interface{ Scan(...interface{}) error }) (*Comment, error) {
+	var c Comment
+	var parentID sql.NullInt64
+	var mentions pq.Int64Array
+	var up uploaderColumns
+	if err := row.Scan(&c.ID, &c.MaterialID, &parentID, &c.AuthorID, &c.Body, &mentions, &c.ReplyCount, &c.Edited, &c.Deleted, &c.CreatedAt, &c.UpdatedAt, &up.firstName, &up.lastName, &up.handle, &up.hideRealName); err != nil {
+		return nil, err
+	}
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		c.ParentID = &id
+	}
+	c.MentionIDs = make([]int, len(mentions))
+	for i, id := range mentions {
+		c.MentionIDs[i] = int(id)
+	}
+	c.Author = up.summary(c.AuthorID)
+	return &c, nil
+}
+
+func (m *CommentModel) Create(ctx context.Context, comment *Comment) error {
+	ctx, done := m.opts.begin(ctx, "CommentModel.Create")
+	defer done()
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := `
+		INSERT INTO comments (material_id, user_id, parent_id, body, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		RETURNING id
+	`
+	if err := tx.QueryRowContext(ctx, query, comment.MaterialID, comment.AuthorID, comment.ParentID, comment.Body, now).Scan(&comment.ID); err != nil {
+		return err
+	}
+	for _, userID := range comment.MentionIDs {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO comment_mentions (comment_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", comment.ID, userID); err != nil {
+			return err
+		}
+	}
+	comment.CreatedAt = now
+	comment.UpdatedAt = now
+	return tx.Commit()
+}
+
+func (m *CommentModel) GetByID(ctx context.Context, id int) (*Comment, error) {
+	ctx, done := m.opts.begin(ctx, "CommentModel.GetByID")
+	defer done()
+	c, err := scanComment(m.db.QueryRowContext(ctx, commentColumns+" WHERE c.id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, sql.ErrNoRows
+	}
+	return c, err
+}
+
+// ListByMaterial returns one page of top-level comments, oldest first, along
+// with the total number of top-level comments on the material.
+func (m *CommentModel) ListByMaterial(ctx context.Context, materialID, limit, offset int) ([]*Comment, int, error) {
+	ctx, done := m.opts.begin(ctx, "CommentModel.ListByMaterial")
+	defer done()
+	var total int
+	if err := m.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM comments WHERE material_id = $1 AND parent_id IS NULL", materialID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	query := commentColumns + `
+		WHERE c.material_id = $1 AND c.parent_id IS NULL
+		ORDER BY c.created_at, c.id
+		LIMIT $2 OFFSET $3
+	`
+	comments, err := m.list(ctx, query, materialID, limit, offset)
+	return comments, total, err
+}
+
+// ListReplies returns one page of direct replies to a comment, oldest first,
+// along with the total number of replies.
+func (m *CommentModel) ListReplies(ctx context.Context, parentID, limit, offset int) ([]*Comment, int, error) {
+	ctx, done := m.opts.begin(ctx, "CommentModel.ListReplies")
+	defer done()
+	var total int
+	if err := m.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM comments WHERE parent_id = $1", parentID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	query := commentColumns + `
+		WHERE c.parent_id = $1
+		ORDER BY c.created_at, c.id
+		LIMIT $2 OFFSET $3
+	`
+	comments, err := m.list(ctx, query, parentID, limit, offset)
+	return comments, total, err
+}
+
+func (m *CommentModel) list(ctx context.Context, query string, args ...interface{}) ([]*Comment, error) {
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []*Comment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// Update replaces a comment's body and mentions, keeping the previous body
+// in the edit history.
+func (m *CommentModel) Update(ctx context.Context, id int, body string, mentionIDs []int) error {
+	ctx, done := m.opts.begin(ctx, "CommentModel.Update")
+	defer done()
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var previous string
+	if err := tx.QueryRowContext(ctx, "SELECT body FROM comments WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id).Scan(&previous); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO comment_edits (comment_id, body, edited_at) VALUES ($1, $2, $3)", id, previous, now); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE comments SET body = $1, edited_at = $2, updated_at = $2 WHERE id = $3", body, now, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM comment_mentions WHERE comment_id = $1 AND NOT (user_id = ANY($2))", id, pq.Array(mentionIDs)); err != nil {
+		return err
+	}
+	for _, userID := range mentionIDs {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO comment_mentions (comment_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", id, userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (m *CommentModel) SoftDelete(ctx context.Context, id int) error {
+	ctx, done := m.opts.begin(ctx, "CommentModel.SoftDelete")
+	defer done()
+	_, err := m.db.ExecContext(ctx, "UPDATE comments SET deleted_at = $1, updated_at = $1 WHERE id = $2 AND deleted_at IS NULL", time.Now(), id)
+	return err
+}
+
+// GetHistory returns the previous bodies of a comment, newest first.
+func (m *CommentModel) GetHistory(ctx context.Context, id int) ([]*CommentEdit, error) {
+	ctx, done := m.opts.begin(ctx, "CommentModel.GetHistory")
+	defer done()
+	rows, err := m.db.QueryContext(ctx, "SELECT body, edited_at FROM comment_edits WHERE comment_id = $1 ORDER BY edited_at DESC", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := []*CommentEdit{}
+	for rows.Next() {
+		var e CommentEdit
+		if err := rows.Scan(&e.Body, &e.EditedAt); err != nil {
+			return nil, err
+		}
+		edits = append(edits, &e)
+	}
+	return edits, rows.Err()
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// UserActivity is everything a user has done on the site, for the personal
+// data export. Uploads include hidden and soft-deleted materials.
+type UserActivity struct {
+	Uploads   []*Material     `json:"uploads"`
+	Votes     []*VoteRecord   `json:"votes"`
+	Bookmarks []*BookmarkItem `json:"bookmarks"`
+	Points    []*PointEvent   `json:"points"`
+}
+
+type VoteRecord struct {
+	MaterialID int       `json:"material_id"`
+	VoteType   string    `json:"vote_type"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type BookmarkItem struct {
+	MaterialID int       `json:"material_id"`
+	Title      string    `json:"title"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type PointEvent struct {
+	Points     int       `json:"points"`
+	Reason     string    `json:"reason"`
+	MaterialID *int      `json:"material_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ExportModel struct {
+	db   *sql.DB
+	opts QueryOptions
+}
+
+func NewExportModel(db *sql.DB, opts QueryOptions) *ExportModel {
+	return &ExportModel{db: db, opts: opts}
+}
+
+// Activity reads the user's activity in one read-only snapshot so the parts
+// of an export agree with each other.
+func (m *ExportModel) Activity(ctx context.Context, userID int) (*UserActivity, error) {
+	ctx, done := m.opts.begin(ctx, "ExportModel.Activity")
+	defer done()
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	a := &UserActivity{Uploads: []*Material{}, Votes: []*VoteRecord{}, Bookmarks: []*BookmarkItem{}, Points: []*PointEvent{}}
+
+	err = collect(ctx, tx, `
+		SELECT id, title, description, subject, college, course, file_url, filename, uploader_id, upload_date, hidden_at IS NOT NULL
+		FROM materials WHERE uploader_id = $1 ORDER BY upload_date`, userID,
+		func(row *sql.Rows) error {
+			var mat Material
+			if err := row.Scan(&mat.ID, &mat.Title, &mat.Description, &mat.Subject, &mat.College, &mat.Course, &mat.FileURL, &mat.Filename, &mat.UploaderID, &mat.UploadDate, &mat.Hidden); err != nil {
+				return err
+			}
+			a.Uploads = append(a.Uploads, &mat)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	err = collect(ctx, tx, "SELECT material_id, vote_type, created_at FROM votes WHERE user_id = $1 ORDER BY created_at", userID,
+		func(row *sql.Rows) error {
+			var v VoteRecord
+			if err := row.Scan(&v.MaterialID, &v.VoteType, &v.CreatedAt); err != nil {
+				return err
+			}
+			a.Votes = append(a.Votes, &v)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	err = collect(ctx, tx, `
+		SELECT b.material_id, m.title, b.created_at
+		FROM bookmarks b JOIN materials m ON m.id = b.material_id
+		WHERE b.user_id = $1 ORDER BY b.created_at`, userID,
+		func(row *sql.Rows) error {
+			var b BookmarkItem
+			if err := row.Scan(&b.MaterialID, &b.Title, &b.CreatedAt); err != nil {
+				return err
+			}
+			a.Bookmarks = append(a.Bookmarks, &b)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	err = collect(ctx, tx, "SELECT points, reason, material_id, created_at FROM point_events WHERE user_id = $1 ORDER BY created_at, id", userID,
+		func(row *sql.Rows) error {
+			var p PointEvent
+			var materialID sql.NullInt64
+			if err := row.Scan(&p.Points, &p.Reason, &materialID, &p.CreatedAt); err != nil {
+				return err
+			}
+			if materialID.Valid {
+				id := int(materialID.Int64)
+				p.MaterialID = &id
+			}
+			a.Points = append(a.Points, &p)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// collect runs query and calls scan for each row.
+func collect(ctx context.Context, tx *sql.Tx, query string, arg interface{}, scan func(*sql.Rows) error) error {
+	rows, err := tx.QueryContext(ctx, query, arg)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
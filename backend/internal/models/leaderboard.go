@@ -0,0 +1,157 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	LeaderboardWeek     = "week"
+	LeaderboardMonth    = "month"
+	LeaderboardSemester = "semester"
+	LeaderboardAllTime  = "all"
+)
+
+// LeaderboardEntry is the public view of a ranked student. It deliberately
+// leaves out email, student number and verification status, and honors the
+// student's hide_real_name setting.
+type LeaderboardEntry struct {
+	Rank        int    `json:"rank"`
+	UserID      int    `json:"user_id"`
+	DisplayName string `json:"display_name"`
+	College     string `json:"college,omitempty"`
+	Course      string `json:"course,omitempty"`
+	Points      int    `json:"points"`
+}
+
+// LeaderboardFilter selects a leaderboard. Since is the start of Window,
+// filled in by the service from its Calendar; zero means all time.
+type LeaderboardFilter struct {
+	Window  string
+	Since   time.Time
+	College string
+	Course  string
+	Limit   int
+}
+
+// Calendar places leaderboard windows. Weeks, months and semesters begin at
+// midnight in Location, and a semester begins on each of SemesterStarts.
+type Calendar struct {
+	Location       *time.Location
+	SemesterStarts []MonthDay
+}
+
+type MonthDay struct {
+	Month time.Month
+	Day   int
+}
+
+// NewCalendar returns the calendar for an IANA time zone name and semester
+// start dates written MM-DD.
+func NewCalendar(zone string, semesterStarts []string) (Calendar, error) {
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return Calendar{}, err
+	}
+	c := Calendar{Location: loc}
+	for _, s := range semesterStarts {
+		t, err := time.Parse("01-02", s)
+		if err != nil {
+			return Calendar{}, fmt.Errorf("semester start %q is not MM-DD", s)
+		}
+		c.SemesterStarts = append(c.SemesterStarts, MonthDay{t.Month(), t.Day()})
+	}
+	if len(c.SemesterStarts) == 0 {
+		return Calendar{}, fmt.Errorf("no semester start dates")
+	}
+	sort.Slice(c.SemesterStarts, func(i, j int) bool {
+		a, b := c.SemesterStarts[i], c.SemesterStarts[j]
+		return a.Month < b.Month || (a.Month == b.Month && a.Day < b.Day)
+	})
+	return c, nil
+}
+
+// WindowStart returns the start of the given leaderboard window relative to
+// now. The all-time window has no start and reports ok=false.
+func (c Calendar) WindowStart(window string, now time.Time) (start time.Time, ok bool, err error) {
+	now = now.In(c.Location)
+	y, mo, d := now.Date()
+	switch window {
+	case LeaderboardWeek:
+		offset := (int(now.Weekday()) + 6) % 7 // Monday-based weeks
+		return time.Date(y, mo, d-offset, 0, 0, 0, 0, c.Location), true, nil
+	case LeaderboardMonth:
+		return time.Date(y, mo, 1, 0, 0, 0, 0, c.Location), true, nil
+	case LeaderboardSemester:
+		// The latest start on or before today, else last year's final one.
+		last := c.SemesterStarts[len(c.SemesterStarts)-1]
+		start := time.Date(y-1, last.Month, last.Day, 0, 0, 0, 0, c.Location)
+		for _, s := range c.SemesterStarts {
+			if t := time.Date(y, s.Month, s.Day, 0, 0, 0, 0, c.Location); !t.After(now) {
+				start = t
+			}
+		}
+		return start, true, nil
+	case LeaderboardAllTime, "":
+		return time.Time{}, false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("unknown leaderboard window %q", window)
+}
+
+// GetLeaderboard ranks students by points earned within the filter's window.
+// It returns the top entries and, if viewerID is ranked in the same board,
+// the viewer's own entry even when it falls outside the top N.
+func (m *UserModel) GetLeaderboard(ctx context.Context, filter LeaderboardFilter, viewerID int) ([]*LeaderboardEntry, *LeaderboardEntry, error) {
+	ctx, done := m.opts.begin(ctx, "UserModel.GetLeaderboard")
+	defer done()
+	since := sql.NullTime{Time: filter.Since, Valid: !filter.Since.IsZero()}
+
+	query := `
+		WITH totals AS (
+			SELECT u.id, u.first_name, u.last_name, COALESCE(u.handle, '') AS handle, u.hide_real_name,
+			       COALESCE(u.college, '') AS college, COALESCE(u.course, '') AS course,
+			       COALESCE(SUM(p.points), 0) AS points
+			FROM users u
+			LEFT JOIN point_events p ON p.user_id = u.id AND ($1::timestamptz IS NULL OR p.created_at >= $1::timestamptz)
+			WHERE u.is_student = true AND u.deleted_at IS NULL
+			AND ($2::text = '' OR u.college = $2)
+			AND ($3::text = '' OR u.course = $3)
+			GROUP BY u.id
+		), ranked AS (
+			SELECT *, RANK() OVER (ORDER BY points DESC) AS rank,
+			       ROW_NUMBER() OVER (ORDER BY points DESC, id) AS position
+			FROM totals
+		)
+		SELECT rank, id, first_name, last_name, handle, hide_real_name, college, course, points, position
+		FROM ranked WHERE position <= $4 OR id = $5
+		ORDER BY position
+	`
+	rows, err := m.db.QueryContext(ctx, query, since, filter.College, filter.Course, filter.Limit, viewerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var entries []*LeaderboardEntry
+	var viewer *LeaderboardEntry
+	for rows.Next() {
+		var e LeaderboardEntry
+		var firstName, lastName, handle string
+		var hideRealName bool
+		var position int
+		if err := rows.Scan(&e.Rank, &e.UserID, &firstName, &lastName, &handle, &hideRealName, &e.College, &e.Course, &e.Points, &position); err != nil {
+			return nil, nil, err
+		}
+		e.DisplayName = DisplayName(firstName, lastName, handle, hideRealName)
+		if e.UserID == viewerID {
+			viewer = &e
+		}
+		if position <= filter.Limit {
+			entries = append(entries, &e)
+		}
+	}
+	return entries, viewer, rows.Err()
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"time"
 )
@@ -17,127 +18,369 @@ type Material struct {
 	UploaderID  int       `json:"uploader_id"`
 	UploadDate  time.Time `json:"upload_date"`
 	VoteCount   int       `json:"vote_count"`
+	Hidden      bool      `json:"hidden,omitempty"`
+
+	Uploader *UploaderSummary `json:"uploader,omitempty"`
 }
 
+// uploaderLive matches materials whose uploader is active or was anonymized
+// when their account was purged. Uploaders still in the deletion grace
+// period are hidden along with their materials.
+const uploaderLive = "(u.deleted_at IS NULL OR u.anonymized_at IS NOT NULL)"
+
 type MaterialModel struct {
-	db *sql.DB
+	db   *sql.DB
+	opts QueryOptions
 }
 
-func NewMaterialModel(db *sql.DB) *MaterialModel {
-	return &MaterialModel{db: db}
+func NewMaterialModel(db *sql.DB, opts QueryOptions) *MaterialModel {
+	return &MaterialModel{db: db, opts: opts}
 }
 
-func (m *MaterialModel) Create(material *Material) error {
+func (m *MaterialModel) Create(ctx context.Context, material *Material) error {
+	ctx, done := m.opts.begin(ctx, "MaterialModel.Create")
+	defer done()
 	query := `
 		INSERT INTO materials (title, description, subject, college, course, file_url, filename, uploader_id, upload_date)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id
 	`
-	return m.db.QueryRow(query, material.Title, material.Description, material.Subject, material.College, material.Course, material.FileURL, material.Filename, material.UploaderID, time.Now()).Scan(&material.ID)
+	return m.db.QueryRowContext(ctx, query, material.Title, material.Description, material.Subject, material.College, material.Course, material.FileURL, material.Filename, material.UploaderID, time.Now()).Scan(&material.ID)
 }
 
-func (m *MaterialModel) GetByID(id int) (*Material, error) {
+// CreateWithPoints inserts the material and awards its uploader points in
+// one transaction, so an upload never exists without its points or the
+// other way round.
+func (m *MaterialModel) CreateWithPoints(ctx context.Context, material *Material, points int) error {
+	ctx, done := m.opts.begin(ctx, "MaterialModel.CreateWithPoints")
+	defer done()
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
-		SELECT id, title, description, subject, college, course, file_url, filename, uploader_id, upload_date,
+		INSERT INTO materials (title, description, subject, college, course, file_url, filename, uploader_id, upload_date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+	if err := tx.QueryRowContext(ctx, query, material.Title, material.Description, material.Subject, material.College, material.Course, material.FileURL, material.Filename, material.UploaderID, time.Now()).Scan(&material.ID); err != nil {
+		return err
+	}
+	if err := awardPoints(ctx, tx, material.UploaderID, points, "upload", &material.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *MaterialModel) GetByID(ctx context.Context, id int) (*Material, error) {
+	ctx, done := m.opts.begin(ctx, "MaterialModel.GetByID")
+	defer done()
+	query := `
+		SELECT m.id, m.title, m.description, m.subject, m.college, m.course, m.file_url, m.filename, m.uploader_id, m.upload_date,
 		       COALESCE((
 		           SELECT SUM(CASE WHEN vote_type = 'UPVOTE' THEN 1 ELSE -1 END)
-		           FROM votes WHERE material_id = materials.id
-		       ), 0) AS vote_count
-		FROM materials WHERE id = $1
-	`
+		           FROM votes WHERE material_id = m.id
+		       ), 0) AS vote_count,
+		       m.hidden_at IS NOT NULL,
+		       u.first_name, u.last_name, COALESCE(u.handle, ''), u.hide_real_name
+		FROM materials m
+		JOIN users u ON u.id = m.uploader_id
+		WHERE m.id = $1 AND m.deleted_at IS NULL AND ` + uploaderLive
 	var mat Material
-	err := m.db.QueryRow(query, id).Scan(&mat.ID, &mat.Title, &mat.Description, &mat.Subject, &mat.College, &mat.Course, &mat.FileURL, &mat.Filename, &mat.UploaderID, &mat.UploadDate, &mat.VoteCount)
+	var up uploaderColumns
+	err := m.db.QueryRowContext(ctx, query, id).Scan(&mat.ID, &mat.Title, &mat.Description, &mat.Subject, &mat.College, &mat.Course, &mat.FileURL, &mat.Filename, &mat.UploaderID, &mat.UploadDate, &mat.VoteCount, &mat.Hidden, &up.firstName, &up.lastName, &up.handle, &up.hideRealName)
 	if err == sql.ErrNoRows {
 		return nil, sql.ErrNoRows
 	}
+	mat.Uploader = up.summary(mat.UploaderID)
 	return &mat, err
 }
 
-func (m *MaterialModel) List() ([]*Material, error) {
+// List returns one page of materials visible to students, newest first,
+// along with their total. A limit of zero returns every material.
+func (m *MaterialModel) List(ctx context.Context, limit, offset int) ([]*Material, int, error) {
+	ctx, done := m.opts.begin(ctx, "MaterialModel.List")
+	defer done()
+	return m.list(ctx, "WHERE m.deleted_at IS NULL AND "+uploaderLive+" AND m.hidden_at IS NULL", limit, offset)
+}
+
+// ListAll returns one page of live materials, including ones hidden by
+// moderators, along with their total.
+func (m *MaterialModel) ListAll(ctx context.Context, limit, offset int) ([]*Material, int, error) {
+	ctx, done := m.opts.begin(ctx, "MaterialModel.ListAll")
+	defer done()
+	return m.list(ctx, "WHERE m.deleted_at IS NULL AND "+uploaderLive, limit, offset)
+}
+
+// ListDeleted returns one page of soft-deleted materials awaiting purge,
+// along with their total.
+func (m *MaterialModel) ListDeleted(ctx context.Context, limit, offset int) ([]*Material, int, error) {
+	ctx, done := m.opts.begin(ctx, "MaterialModel.ListDeleted")
+	defer done()
+	return m.list(ctx, "WHERE m.deleted_at IS NOT NULL", limit, offset)
+}
+
+func (m *MaterialModel) list(ctx context.Context, where string, limit, offset int) ([]*Material, int, error) {
+	var total int
+	if err := m.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM materials m JOIN users u ON u.id = m.uploader_id "+where).Scan(&total); err != nil {
+		return nil, 0, err
+	}
 	query := `
-		SELECT id, title, description, subject, college, course, file_url, filename, uploader_id, upload_date,
+		SELECT m.id, m.title, m.description, m.subject, m.college, m.course, m.file_url, m.filename, m.uploader_id, m.upload_date,
 		       COALESCE((
 		           SELECT SUM(CASE WHEN vote_type = 'UPVOTE' THEN 1 ELSE -1 END)
-		           FROM votes WHERE material_id = materials.id
-		       ), 0) AS vote_count
-		FROM materials ORDER BY upload_date DESC
+		           FROM votes WHERE material_id = m.id
+		       ), 0) AS vote_count,
+		       m.hidden_at IS NOT NULL,
+		       u.first_name, u.last_name, COALESCE(u.handle, ''), u.hide_real_name
+		FROM materials m
+		JOIN users u ON u.id = m.uploader_id
+		` + where + `
+		ORDER BY m.upload_date DESC, m.id DESC
+		LIMIT $1 OFFSET $2
 	`
-	rows, err := m.db.Query(query)
+	rows, err := m.db.QueryContext(ctx, query, limitArg(limit), offset)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var materials []*Material
+	materials := []*Material{}
 	for rows.Next() {
 		var m Material
-		if err := rows.Scan(&m.ID, &m.Title, &m.Description, &m.Subject, &m.College, &m.Course, &m.FileURL, &m.Filename, &m.UploaderID, &m.UploadDate, &m.VoteCount); err != nil {
-			return nil, err
+		var up uploaderColumns
+		if err := rows.Scan(&m.ID, &m.Title, &m.Description, &m.Subject, &m.College, &m.Course, &m.FileURL, &m.Filename, &m.UploaderID, &m.UploadDate, &m.VoteCount, &m.Hidden, &up.firstName, &up.lastName, &up.handle, &up.hideRealName); err != nil {
+			return nil, 0, err
 		}
+		m.Uploader = up.summary(m.UploaderID)
 		materials = append(materials, &m)
 	}
-	return materials, rows.Err()
+	return materials, total, rows.Err()
 }
 
-func (m *MaterialModel) Update(material *Material) error {
+// Update replaces the editable fields of a material. It returns
+// sql.ErrNoRows if the material is missing or soft-deleted.
+func (m *MaterialModel) Update(ctx context.Context, material *Material) error {
+	ctx, done := m.opts.begin(ctx, "MaterialModel.Update")
+	defer done()
 	query := `
 		UPDATE materials SET title = $1, description = $2, subject = $3, college = $4, course = $5, file_url = $6, filename = $7
-		WHERE id = $8
+		WHERE id = $8 AND deleted_at IS NULL
 	`
-	_, err := m.db.Exec(query, material.Title, material.Description, material.Subject, material.College, material.Course, material.FileURL, material.Filename, material.ID)
-	return err
+	result, err := m.db.ExecContext(ctx, query, material.Title, material.Description, material.Subject, material.College, material.Course, material.FileURL, material.Filename, material.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
 }
 
-func (m *MaterialModel) Delete(id int) error {
-	_, err := m.db.Exec("DELETE FROM materials WHERE id = $1", id)
-	return err
+// Delete soft-deletes a material and reverses the points it earned. The row
+// is kept until the retention job purges it.
+func (m *MaterialModel) Delete(ctx context.Context, id int) error {
+	ctx, done := m.opts.begin(ctx, "MaterialModel.Delete")
+	defer done()
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteMaterial(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Restore undoes a soft delete and gives back the points reversed by it.
+func (m *MaterialModel) Restore(ctx context.Context, id int) error {
+	ctx, done := m.opts.begin(ctx, "MaterialModel.Restore")
+	defer done()
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE materials SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	query := `
+		SELECT user_id, -SUM(points) FROM point_events
+		WHERE material_id = $1 AND reason IN ('material_removed', 'material_restored')
+		GROUP BY user_id
+	`
+	if err := adjustMaterialPoints(ctx, tx, id, query, "material_restored"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleteMaterial soft-deletes a material inside tx and records a negative
+// point event for every user whose points came from it. Badges already
+// awarded are kept.
+func deleteMaterial(ctx context.Context, tx *sql.Tx, id int) error {
+	result, err := tx.ExecContext(ctx, "UPDATE materials SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL", time.Now(), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	query := "SELECT user_id, -SUM(points) FROM point_events WHERE material_id = $1 GROUP BY user_id"
+	return adjustMaterialPoints(ctx, tx, id, query, "material_removed")
 }
 
-func (m *MaterialModel) Vote(materialID, userID int, voteType string) error {
+// adjustMaterialPoints applies the per-user point deltas returned by query
+// (user_id, delta) as point events tied to the material.
+func adjustMaterialPoints(ctx context.Context, tx *sql.Tx, materialID int, query, reason string) error {
+	rows, err := tx.QueryContext(ctx, query, materialID)
+	if err != nil {
+		return err
+	}
+	deltas := map[int]int{}
+	for rows.Next() {
+		var userID, delta int
+		if err := rows.Scan(&userID, &delta); err != nil {
+			rows.Close()
+			return err
+		}
+		if delta != 0 {
+			deltas[userID] = delta
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for userID, delta := range deltas {
+		if _, err := tx.ExecContext(ctx, "UPDATE users SET points = points + $1, updated_at = $2 WHERE id = $3", delta, now, userID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO point_events (user_id, points, reason, material_id, created_at) VALUES ($1, $2, $3, $4, $5)", userID, delta, reason, materialID, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MaterialModel) Vote(ctx context.Context, materialID, userID int, voteType string) error {
+	ctx, done := m.opts.begin(ctx, "MaterialModel.Vote")
+	defer done()
 	query := `
 		INSERT INTO votes (material_id, user_id, vote_type, created_at)
 		VALUES ($1, $2, $3, $4)
 		ON CONFLICT (material_id, user_id) DO UPDATE SET vote_type = $3, created_at = $4
 	`
-	_, err := m.db.Exec(query, materialID, userID, voteType, time.Now())
+	_, err := m.db.ExecContext(ctx, query, materialID, userID, voteType, time.Now())
 	return err
 }
 
-func (m *MaterialModel) Bookmark(materialID, userID int) error {
+func (m *MaterialModel) Bookmark(ctx context.Context, materialID, userID int) error {
+	ctx, done := m.opts.begin(ctx, "MaterialModel.Bookmark")
+	defer done()
 	query := `
 		INSERT INTO bookmarks (material_id, user_id, created_at)
 		VALUES ($1, $2, $3)
 		ON CONFLICT (material_id, user_id) DO NOTHING
 	`
-	_, err := m.db.Exec(query, materialID, userID, time.Now())
+	_, err := m.db.ExecContext(ctx, query, materialID, userID, time.Now())
 	return err
 }
 
-func (m *MaterialModel) GetBookmarks(userID int) ([]*Material, error) {
+// GetBookmarks returns one page of the user's bookmarked materials, most
+// recently bookmarked first, along with their total.
+func (m *MaterialModel) GetBookmarks(ctx context.Context, userID, limit, offset int) ([]*Material, int, error) {
+	ctx, done := m.opts.begin(ctx, "MaterialModel.GetBookmarks")
+	defer done()
+	from := `
+		FROM materials m
+		JOIN bookmarks b ON m.id = b.material_id
+		JOIN users u ON u.id = m.uploader_id
+		WHERE b.user_id = $1 AND m.hidden_at IS NULL AND m.deleted_at IS NULL AND ` + uploaderLive
+	var total int
+	if err := m.db.QueryRowContext(ctx, "SELECT COUNT(*)"+from, userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
 	query := `
 		SELECT m.id, m.title, m.description, m.subject, m.college, m.course, m.file_url, m.filename, m.uploader_id, m.upload_date,
 		       COALESCE((
 		           SELECT SUM(CASE WHEN vote_type = 'UPVOTE' THEN 1 ELSE -1 END)
 		           FROM votes WHERE material_id = m.id
+		       ), 0) AS vote_count,
+		       u.first_name, u.last_name, COALESCE(u.handle, ''), u.hide_real_name` + from + `
+		ORDER BY b.created_at DESC, m.id DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := m.db.QueryContext(ctx, query, userID, limitArg(limit), offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	bookmarks := []*Material{}
+	for rows.Next() {
+		var m Material
+		var up uploaderColumns
+		if err := rows.Scan(&m.ID, &m.Title, &m.Description, &m.Subject, &m.College, &m.Course, &m.FileURL, &m.Filename, &m.UploaderID, &m.UploadDate, &m.VoteCount, &up.firstName, &up.lastName, &up.handle, &up.hideRealName); err != nil {
+			return nil, 0, err
+		}
+		m.Uploader = up.summary(m.UploaderID)
+		bookmarks = append(bookmarks, &m)
+	}
+	return bookmarks, total, rows.Err()
+}
+
+func (m *MaterialModel) ListByUploader(ctx context.Context, uploaderID int) ([]*Material, error) {
+	ctx, done := m.opts.begin(ctx, "MaterialModel.ListByUploader")
+	defer done()
+	query := `
+		SELECT id, title, description, subject, college, course, file_url, filename, uploader_id, upload_date,
+		       COALESCE((
+		           SELECT SUM(CASE WHEN vote_type = 'UPVOTE' THEN 1 ELSE -1 END)
+		           FROM votes WHERE material_id = materials.id
 		       ), 0) AS vote_count
-		FROM materials m
-		JOIN bookmarks b ON m.id = b.material_id
-		WHERE b.user_id = $1
-		ORDER BY b.created_at DESC
+		FROM materials WHERE uploader_id = $1 AND hidden_at IS NULL AND deleted_at IS NULL
+		ORDER BY upload_date DESC
 	`
-	rows, err := m.db.Query(query, userID)
+	rows, err := m.db.QueryContext(ctx, query, uploaderID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var bookmarks []*Material
+	materials := []*Material{}
 	for rows.Next() {
 		var m Material
 		if err := rows.Scan(&m.ID, &m.Title, &m.Description, &m.Subject, &m.College, &m.Course, &m.FileURL, &m.Filename, &m.UploaderID, &m.UploadDate, &m.VoteCount); err != nil {
 			return nil, err
 		}
-		bookmarks = append(bookmarks, &m)
+		materials = append(materials, &m)
+	}
+	return materials, rows.Err()
+}
+
+// uploaderColumns holds the users columns joined into material queries.
+type uploaderColumns struct {
+	firstName    string
+	lastName     string
+	handle       string
+	hideRealName bool
+}
+
+func (c uploaderColumns) summary(uploaderID int) *UploaderSummary {
+	return &UploaderSummary{
+		ID:          uploaderID,
+		DisplayName: DisplayName(c.firstName, c.lastName, c.handle, c.hideRealName),
 	}
-	return bookmarks, rows.Err()
 }
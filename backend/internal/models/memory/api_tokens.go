@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+type APITokenRepository struct {
+	s *Store
+}
+
+var _ models.APITokenRepository = (*APITokenRepository)(nil)
+
+func copyAPIToken(rec *apiTokenRecord) *models.APIToken {
+	t := rec.token
+	t.Scopes = slices.Clone(t.Scopes)
+	if t.LastUsedAt != nil {
+		at := *t.LastUsedAt
+		t.LastUsedAt = &at
+	}
+	return &t
+}
+
+func (r *APITokenRepository) Create(ctx context.Context, t *models.APIToken, hash string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.users[t.UserID]; !ok {
+		return sql.ErrNoRows
+	}
+	t.ID = r.s.nextAPITokenID
+	r.s.nextAPITokenID++
+	t.CreatedAt = time.Now()
+	rec := &apiTokenRecord{token: *t, hash: hash}
+	rec.token.Scopes = slices.Clone(t.Scopes)
+	r.s.apiTokens = append(r.s.apiTokens, rec)
+	return nil
+}
+
+func (r *APITokenRepository) ListByUser(ctx context.Context, userID int) ([]*models.APIToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	tokens := []*models.APIToken{}
+	for _, rec := range r.s.apiTokens {
+		if rec.token.UserID == userID {
+			tokens = append(tokens, copyAPIToken(rec))
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if !tokens[i].CreatedAt.Equal(tokens[j].CreatedAt) {
+			return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+		}
+		return tokens[i].ID > tokens[j].ID
+	})
+	return tokens, nil
+}
+
+func (r *APITokenRepository) CountByUser(ctx context.Context, userID int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	n := 0
+	for _, rec := range r.s.apiTokens {
+		if rec.token.UserID == userID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *APITokenRepository) Delete(ctx context.Context, userID, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	n := len(r.s.apiTokens)
+	r.s.apiTokens = slices.DeleteFunc(r.s.apiTokens, func(rec *apiTokenRecord) bool {
+		return rec.token.ID == id && rec.token.UserID == userID
+	})
+	if len(r.s.apiTokens) == n {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *APITokenRepository) Authenticate(ctx context.Context, hash string) (*models.TokenGrant, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	for _, rec := range r.s.apiTokens {
+		if rec.hash != hash || !rec.token.ExpiresAt.After(now) {
+			continue
+		}
+		user, ok := r.s.liveUser(rec.token.UserID)
+		if !ok {
+			return nil, sql.ErrNoRows
+		}
+		rec.token.LastUsedAt = &now
+		return &models.TokenGrant{
+			TokenID:     rec.token.ID,
+			UserID:      rec.token.UserID,
+			IsStudent:   user.user.IsStudent,
+			IsModerator: user.user.IsModerator,
+			Scopes:      slices.Clone(rec.token.Scopes),
+		}, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+// deleteAPITokens removes every personal access token of the user. Callers
+// hold s.mu.
+func (s *Store) deleteAPITokens(userID int) {
+	s.apiTokens = slices.DeleteFunc(s.apiTokens, func(rec *apiTokenRecord) bool { return rec.token.UserID == userID })
+}
@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+type AuditRepository struct {
+	s *Store
+}
+
+var _ models.AuditRepository = (*AuditRepository)(nil)
+
+func (r *AuditRepository) Create(ctx context.Context, e *models.AuditEvent) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if len(e.Diff) == 0 {
+		e.Diff = json.RawMessage("{}")
+	}
+	e.ID = r.s.nextAuditID
+	r.s.nextAuditID++
+	e.CreatedAt = time.Now()
+	r.s.auditEvents = append(r.s.auditEvents, *copyEvent(e))
+	return nil
+}
+
+func (r *AuditRepository) List(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEvent, int, error) {
+	matched := r.matching(filter)
+	slices.Reverse(matched)
+	events := []*models.AuditEvent{}
+	for _, e := range page(matched, filter.Limit, filter.Offset) {
+		events = append(events, copyEvent(&e))
+	}
+	return events, len(matched), nil
+}
+
+func (r *AuditRepository) Each(ctx context.Context, filter models.AuditFilter, fn func(*models.AuditEvent) error) error {
+	for _, e := range r.matching(filter) {
+		if err := fn(copyEvent(&e)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matching returns the events filter selects, oldest first.
+func (r *AuditRepository) matching(f models.AuditFilter) []models.AuditEvent {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var events []models.AuditEvent
+	for _, e := range r.s.auditEvents {
+		switch {
+		case f.ActorID != nil && (e.ActorID == nil || *e.ActorID != *f.ActorID),
+			f.Action != "" && e.Action != f.Action,
+			f.TargetType != "" && e.TargetType != f.TargetType,
+			f.TargetID != nil && (e.TargetID == nil || *e.TargetID != *f.TargetID),
+			f.Since != nil && e.CreatedAt.Before(*f.Since),
+			f.Until != nil && !e.CreatedAt.Before(*f.Until):
+			continue
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+// copyEvent returns a copy of e that shares no memory with it.
+func copyEvent(e *models.AuditEvent) *models.AuditEvent {
+	c := *e
+	if e.ActorID != nil {
+		id := *e.ActorID
+		c.ActorID = &id
+	}
+	if e.TargetID != nil {
+		id := *e.TargetID
+		c.TargetID = &id
+	}
+	c.Diff = slices.Clone(e.Diff)
+	return &c
+}
@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+type CommentRepository struct {
+	s *Store
+}
+
+var _ models.CommentRepository = (*CommentRepository)(nil)
+
+// view copies a comment with its author, mentions and reply count filled
+// in. Deleted comments lose their body, as in CommentModel. Callers hold
+// s.mu.
+func (r *CommentRepository) view(rec *commentRecord) *models.Comment {
+	c := rec.comment
+	if c.ParentID != nil {
+		id := *c.ParentID
+		c.ParentID = &id
+	}
+	c.MentionIDs = slices.Clone(rec.mentions)
+	slices.Sort(c.MentionIDs)
+	c.ReplyCount = 0
+	for _, other := range r.s.comments {
+		if other.comment.ParentID != nil && *other.comment.ParentID == c.ID {
+			c.ReplyCount++
+		}
+	}
+	c.Edited = rec.editedAt != nil
+	c.Deleted = rec.deletedAt != nil
+	if c.Deleted {
+		c.Body = ""
+	}
+	c.Author = r.s.uploaderSummary(c.AuthorID)
+	return &c
+}
+
+func (r *CommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.materials[comment.MaterialID]; !ok {
+		return sql.ErrNoRows
+	}
+	if _, ok := r.s.users[comment.AuthorID]; !ok {
+		return sql.ErrNoRows
+	}
+	if comment.ParentID != nil && r.s.comments[*comment.ParentID] == nil {
+		return sql.ErrNoRows
+	}
+	now := time.Now()
+	comment.ID = r.s.nextCommentID
+	r.s.nextCommentID++
+	comment.CreatedAt = now
+	comment.UpdatedAt = now
+	rec := &commentRecord{comment: *comment}
+	for _, id := range comment.MentionIDs {
+		if !slices.Contains(rec.mentions, id) {
+			rec.mentions = append(rec.mentions, id)
+		}
+	}
+	r.s.comments[comment.ID] = rec
+	return nil
+}
+
+func (r *CommentRepository) GetByID(ctx context.Context, id int) (*models.Comment, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	rec, ok := r.s.comments[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return r.view(rec), nil
+}
+
+func (r *CommentRepository) ListByMaterial(ctx context.Context, materialID, limit, offset int) ([]*models.Comment, int, error) {
+	comments, total := r.list(func(c *models.Comment) bool {
+		return c.MaterialID == materialID && c.ParentID == nil
+	}, limit, offset)
+	return comments, total, nil
+}
+
+func (r *CommentRepository) ListReplies(ctx context.Context, parentID, limit, offset int) ([]*models.Comment, int, error) {
+	comments, total := r.list(func(c *models.Comment) bool {
+		return c.ParentID != nil && *c.ParentID == parentID
+	}, limit, offset)
+	return comments, total, nil
+}
+
+// list returns one page of the matching comments, oldest first, and the
+// number of matches.
+func (r *CommentRepository) list(match func(*models.Comment) bool, limit, offset int) ([]*models.Comment, int) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var matched []*commentRecord
+	for _, rec := range r.s.comments {
+		if match(&rec.comment) {
+			matched = append(matched, rec)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i].comment, matched[j].comment
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+	comments := []*models.Comment{}
+	for _, rec := range page(matched, limit, offset) {
+		comments = append(comments, r.view(rec))
+	}
+	return comments, len(matched)
+}
+
+func (r *CommentRepository) Update(ctx context.Context, id int, body string, mentionIDs []int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	rec, ok := r.s.comments[id]
+	if !ok || rec.deletedAt != nil {
+		return sql.ErrNoRows
+	}
+	now := time.Now()
+	rec.edits = append(rec.edits, models.CommentEdit{Body: rec.comment.Body, EditedAt: now})
+	rec.comment.Body = body
+	rec.comment.UpdatedAt = now
+	rec.editedAt = &now
+	rec.mentions = nil
+	for _, userID := range mentionIDs {
+		if !slices.Contains(rec.mentions, userID) {
+			rec.mentions = append(rec.mentions, userID)
+		}
+	}
+	return nil
+}
+
+func (r *CommentRepository) SoftDelete(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if rec, ok := r.s.comments[id]; ok && rec.deletedAt == nil {
+		now := time.Now()
+		rec.deletedAt = &now
+		rec.comment.UpdatedAt = now
+	}
+	return nil
+}
+
+func (r *CommentRepository) GetHistory(ctx context.Context, id int) ([]*models.CommentEdit, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	edits := []*models.CommentEdit{}
+	if rec, ok := r.s.comments[id]; ok {
+		for i := len(rec.edits) - 1; i >= 0; i-- {
+			e := rec.edits[i]
+			edits = append(edits, &e)
+		}
+	}
+	return edits, nil
+}
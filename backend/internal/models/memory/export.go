@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+type ExportRepository struct {
+	s *Store
+}
+
+var _ models.ExportRepository = (*ExportRepository)(nil)
+
+func (r *ExportRepository) Activity(ctx context.Context, userID int) (*models.UserActivity, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	a := &models.UserActivity{Uploads: []*models.Material{}, Votes: []*models.VoteRecord{}, Bookmarks: []*models.BookmarkItem{}, Points: []*models.PointEvent{}}
+
+	for _, rec := range r.s.materials {
+		if rec.material.UploaderID != userID {
+			continue
+		}
+		m := rec.material
+		m.VoteCount = 0
+		m.Uploader = nil
+		m.Hidden = rec.hiddenAt != nil
+		a.Uploads = append(a.Uploads, &m)
+	}
+	sort.Slice(a.Uploads, func(i, j int) bool { return a.Uploads[i].UploadDate.Before(a.Uploads[j].UploadDate) })
+
+	for key, v := range r.s.votes {
+		if key.userID == userID {
+			a.Votes = append(a.Votes, &models.VoteRecord{MaterialID: key.materialID, VoteType: v.voteType, CreatedAt: v.createdAt})
+		}
+	}
+	sort.Slice(a.Votes, func(i, j int) bool { return a.Votes[i].CreatedAt.Before(a.Votes[j].CreatedAt) })
+
+	for key, at := range r.s.bookmarks {
+		if key.userID == userID {
+			a.Bookmarks = append(a.Bookmarks, &models.BookmarkItem{MaterialID: key.materialID, Title: r.s.materials[key.materialID].material.Title, CreatedAt: at})
+		}
+	}
+	sort.Slice(a.Bookmarks, func(i, j int) bool { return a.Bookmarks[i].CreatedAt.Before(a.Bookmarks[j].CreatedAt) })
+
+	for _, e := range r.s.pointEvents {
+		if e.userID != userID {
+			continue
+		}
+		p := &models.PointEvent{Points: e.points, Reason: e.reason, CreatedAt: e.createdAt}
+		if e.materialID != nil {
+			id := *e.materialID
+			p.MaterialID = &id
+		}
+		a.Points = append(a.Points, p)
+	}
+	return a, nil
+}
@@ -0,0 +1,257 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+type MaterialRepository struct {
+	s *Store
+}
+
+var _ models.MaterialRepository = (*MaterialRepository)(nil)
+
+// view copies a material with its vote count, hidden flag and uploader
+// summary filled in. Callers hold s.mu.
+func (r *MaterialRepository) view(rec *materialRecord) *models.Material {
+	m := rec.material
+	m.VoteCount = 0
+	for key, v := range r.s.votes {
+		if key.materialID == m.ID {
+			if v.voteType == "UPVOTE" {
+				m.VoteCount++
+			} else {
+				m.VoteCount--
+			}
+		}
+	}
+	m.Hidden = rec.hiddenAt != nil
+	m.Uploader = r.s.uploaderSummary(m.UploaderID)
+	return &m
+}
+
+// visible reports whether students can see a material: it and its uploader
+// are live and it is not hidden. Callers hold s.mu.
+func (r *MaterialRepository) visible(rec *materialRecord) bool {
+	return rec.deletedAt == nil && rec.hiddenAt == nil && r.s.uploaderLive(rec.material.UploaderID)
+}
+
+func (r *MaterialRepository) Create(ctx context.Context, material *models.Material) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.users[material.UploaderID]; !ok {
+		return sql.ErrNoRows
+	}
+	material.ID = r.s.nextMaterialID
+	r.s.nextMaterialID++
+	rec := &materialRecord{material: *material}
+	rec.material.UploadDate = time.Now()
+	r.s.materials[material.ID] = rec
+	return nil
+}
+
+func (r *MaterialRepository) CreateWithPoints(ctx context.Context, material *models.Material, points int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.users[material.UploaderID]; !ok {
+		return sql.ErrNoRows
+	}
+	material.ID = r.s.nextMaterialID
+	r.s.nextMaterialID++
+	rec := &materialRecord{material: *material}
+	rec.material.UploadDate = time.Now()
+	r.s.materials[material.ID] = rec
+	r.s.awardPoints(material.UploaderID, points, "upload", &material.ID, rec.material.UploadDate)
+	return nil
+}
+
+func (r *MaterialRepository) GetByID(ctx context.Context, id int) (*models.Material, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	rec, ok := r.s.materials[id]
+	if !ok || rec.deletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+	if !r.s.uploaderLive(rec.material.UploaderID) {
+		return nil, sql.ErrNoRows
+	}
+	return r.view(rec), nil
+}
+
+func (r *MaterialRepository) list(match func(*materialRecord) bool) []*models.Material {
+	var materials []*models.Material
+	for _, rec := range r.s.materials {
+		if match(rec) {
+			materials = append(materials, r.view(rec))
+		}
+	}
+	sort.Slice(materials, func(i, j int) bool {
+		if !materials[i].UploadDate.Equal(materials[j].UploadDate) {
+			return materials[i].UploadDate.After(materials[j].UploadDate)
+		}
+		return materials[i].ID > materials[j].ID
+	})
+	return materials
+}
+
+func (r *MaterialRepository) List(ctx context.Context, limit, offset int) ([]*models.Material, int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	materials := r.list(r.visible)
+	return pageOf(materials, limit, offset), len(materials), nil
+}
+
+func (r *MaterialRepository) ListAll(ctx context.Context, limit, offset int) ([]*models.Material, int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	materials := r.list(func(rec *materialRecord) bool {
+		return rec.deletedAt == nil && r.s.uploaderLive(rec.material.UploaderID)
+	})
+	return pageOf(materials, limit, offset), len(materials), nil
+}
+
+func (r *MaterialRepository) ListDeleted(ctx context.Context, limit, offset int) ([]*models.Material, int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	materials := r.list(func(rec *materialRecord) bool { return rec.deletedAt != nil })
+	return pageOf(materials, limit, offset), len(materials), nil
+}
+
+func (r *MaterialRepository) ListByUploader(ctx context.Context, uploaderID int) ([]*models.Material, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	materials := r.list(func(rec *materialRecord) bool {
+		return rec.material.UploaderID == uploaderID && rec.deletedAt == nil && rec.hiddenAt == nil
+	})
+	if materials == nil {
+		materials = []*models.Material{}
+	}
+	for _, m := range materials {
+		m.Uploader = nil
+	}
+	return materials, nil
+}
+
+func (r *MaterialRepository) Update(ctx context.Context, material *models.Material) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	rec, ok := r.s.materials[material.ID]
+	if !ok || rec.deletedAt != nil {
+		return sql.ErrNoRows
+	}
+	m := &rec.material
+	m.Title = material.Title
+	m.Description = material.Description
+	m.Subject = material.Subject
+	m.College = material.College
+	m.Course = material.Course
+	m.FileURL = material.FileURL
+	m.Filename = material.Filename
+	return nil
+}
+
+func (r *MaterialRepository) Delete(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.delete(id)
+}
+
+// delete soft-deletes a material and reverses the points it earned, like
+// deleteMaterial in package models. Callers hold s.mu.
+func (r *MaterialRepository) delete(id int) error {
+	rec, ok := r.s.materials[id]
+	if !ok || rec.deletedAt != nil {
+		return sql.ErrNoRows
+	}
+	now := time.Now()
+	rec.deletedAt = &now
+	r.adjustPoints(id, "material_removed", now, func(pointEvent) bool { return true })
+	return nil
+}
+
+func (r *MaterialRepository) Restore(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	rec, ok := r.s.materials[id]
+	if !ok || rec.deletedAt == nil {
+		return sql.ErrNoRows
+	}
+	rec.deletedAt = nil
+	r.adjustPoints(id, "material_restored", time.Now(), func(e pointEvent) bool {
+		return e.reason == "material_removed" || e.reason == "material_restored"
+	})
+	return nil
+}
+
+// adjustPoints negates, per user, the sum of the material's point events
+// selected by include, mirroring MaterialModel. Callers hold s.mu.
+func (r *MaterialRepository) adjustPoints(materialID int, reason string, now time.Time, include func(pointEvent) bool) {
+	sums := map[int]int{}
+	for _, e := range r.s.pointEvents {
+		if e.materialID != nil && *e.materialID == materialID && include(e) {
+			sums[e.userID] += e.points
+		}
+	}
+	for userID, sum := range sums {
+		if sum != 0 {
+			r.s.addPoints(userID, -sum, reason, &materialID, now)
+		}
+	}
+}
+
+func (r *MaterialRepository) Vote(ctx context.Context, materialID, userID int, voteType string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.materials[materialID]; !ok {
+		return sql.ErrNoRows
+	}
+	r.s.votes[pair{materialID, userID}] = vote{voteType: voteType, createdAt: time.Now()}
+	return nil
+}
+
+func (r *MaterialRepository) Bookmark(ctx context.Context, materialID, userID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.materials[materialID]; !ok {
+		return sql.ErrNoRows
+	}
+	key := pair{materialID, userID}
+	if _, ok := r.s.bookmarks[key]; !ok {
+		r.s.bookmarks[key] = time.Now()
+	}
+	return nil
+}
+
+func (r *MaterialRepository) GetBookmarks(ctx context.Context, userID, limit, offset int) ([]*models.Material, int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	type bookmarked struct {
+		material *models.Material
+		at       time.Time
+	}
+	var marks []bookmarked
+	for key, at := range r.s.bookmarks {
+		if key.userID != userID {
+			continue
+		}
+		if rec, ok := r.s.materials[key.materialID]; ok && r.visible(rec) {
+			marks = append(marks, bookmarked{r.view(rec), at})
+		}
+	}
+	sort.Slice(marks, func(i, j int) bool {
+		if !marks[i].at.Equal(marks[j].at) {
+			return marks[i].at.After(marks[j].at)
+		}
+		return marks[i].material.ID > marks[j].material.ID
+	})
+
+	materials := []*models.Material{}
+	for _, b := range page(marks, limit, offset) {
+		materials = append(materials, b.material)
+	}
+	return materials, len(marks), nil
+}
@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+	"github.com/ISKOnnect/iskonnect-web/internal/models/memory"
+	"github.com/ISKOnnect/iskonnect-web/internal/models/repotest"
+)
+
+func TestContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) models.Repositories {
+		return memory.NewStore().Repositories()
+	})
+}
@@ -0,0 +1,149 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+type ReportRepository struct {
+	s *Store
+}
+
+var _ models.ReportRepository = (*ReportRepository)(nil)
+
+// pending reports whether a report still awaits a moderator.
+func (rec *reportRecord) pending() bool {
+	return rec.report.Status == models.ReportOpen || rec.report.Status == models.ReportReviewing
+}
+
+func (r *ReportRepository) Create(ctx context.Context, materialID, reporterID int, reason, details string) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.materials[materialID]; !ok {
+		return 0, sql.ErrNoRows
+	}
+	if _, ok := r.s.users[reporterID]; !ok {
+		return 0, sql.ErrNoRows
+	}
+	for _, rec := range r.s.reports {
+		if m := rec.report.MaterialID; m != nil && *m == materialID && rec.reporterID == reporterID && rec.pending() {
+			return 0, sql.ErrNoRows
+		}
+	}
+	id := r.s.nextReportID
+	r.s.nextReportID++
+	r.s.reports[id] = &reportRecord{
+		report: models.Report{
+			ID:         id,
+			MaterialID: &materialID,
+			Reason:     reason,
+			Details:    details,
+			Status:     models.ReportOpen,
+			CreatedAt:  time.Now(),
+		},
+		reporterID: reporterID,
+	}
+	return id, nil
+}
+
+func (r *ReportRepository) List(ctx context.Context, filter models.ReportFilter) ([]*models.Report, int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var matched []*reportRecord
+	for _, rec := range r.s.reports {
+		if (filter.Status == "" || rec.report.Status == filter.Status) && (filter.Reason == "" || rec.report.Reason == filter.Reason) {
+			matched = append(matched, rec)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i].report, matched[j].report
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+
+	reports := []*models.Report{}
+	for _, rec := range page(matched, filter.Limit, filter.Offset) {
+		rep := rec.report
+		rep.MaterialTitle, rep.MaterialHidden = "", false
+		if rep.MaterialID != nil {
+			id := *rep.MaterialID
+			rep.MaterialID = &id
+			if m, ok := r.s.materials[id]; ok {
+				rep.MaterialTitle = m.material.Title
+				rep.MaterialHidden = m.hiddenAt != nil
+			}
+		}
+		rep.Reporter = r.s.uploaderSummary(rec.reporterID)
+		reports = append(reports, &rep)
+	}
+	return reports, len(matched), nil
+}
+
+func (r *ReportRepository) SetStatus(ctx context.Context, id int, status string, moderatorID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	rec, ok := r.s.reports[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	rec.handle(status, rec.report.Resolution, moderatorID, time.Now())
+	return nil
+}
+
+func (rec *reportRecord) handle(status, resolution string, moderatorID int, now time.Time) {
+	rec.report.Status = status
+	rec.report.Resolution = resolution
+	rec.report.HandledBy = &moderatorID
+	rec.report.HandledAt = &now
+}
+
+// Apply mirrors ReportModel.Apply. The material is acted on before any
+// report changes, so a failed action leaves everything as it was.
+func (r *ReportRepository) Apply(ctx context.Context, id int, action string, moderatorID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	rec, ok := r.s.reports[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+
+	now := time.Now()
+	if action == models.ModerationDismiss {
+		rec.handle(models.ReportDismissed, action, moderatorID, now)
+		return nil
+	}
+
+	if rec.report.MaterialID == nil {
+		return models.ErrMaterialGone
+	}
+	materialID := *rec.report.MaterialID
+	material := r.s.materials[materialID]
+	switch action {
+	case models.ModerationHide:
+		if material.hiddenAt == nil {
+			material.hiddenAt = &now
+		}
+	case models.ModerationRestore:
+		material.hiddenAt = nil
+	case models.ModerationDelete:
+		if err := (&MaterialRepository{s: r.s}).delete(materialID); err != nil {
+			return err
+		}
+	default:
+		return errors.New("unknown moderation action")
+	}
+
+	for _, other := range r.s.reports {
+		if other == rec || (other.report.MaterialID != nil && *other.report.MaterialID == materialID && other.pending()) {
+			other.handle(models.ReportResolved, action, moderatorID, now)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+type RetentionRepository struct {
+	s *Store
+}
+
+var _ models.RetentionRepository = (*RetentionRepository)(nil)
+
+func (r *RetentionRepository) Purge(cutoff time.Time, anonymize bool) (*models.PurgeResult, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var userIDs []int
+	for id, rec := range r.s.users {
+		if rec.deletedAt != nil && rec.deletedAt.Before(cutoff) && rec.anonymizedAt == nil {
+			userIDs = append(userIDs, id)
+		}
+	}
+
+	result := &models.PurgeResult{}
+	var materialIDs []int
+	for id, rec := range r.s.materials {
+		purged := rec.deletedAt != nil && rec.deletedAt.Before(cutoff)
+		if purged || (!anonymize && slices.Contains(userIDs, rec.material.UploaderID)) {
+			materialIDs = append(materialIDs, id)
+		}
+	}
+	sort.Ints(materialIDs)
+	for _, id := range materialIDs {
+		result.FileURLs = append(result.FileURLs, r.s.materials[id].material.FileURL)
+		r.s.purgeMaterial(id)
+	}
+	result.Materials = len(materialIDs)
+
+	now := time.Now()
+	for _, id := range userIDs {
+		r.s.forgetAuditIPs(id)
+		if anonymize {
+			r.s.anonymizeUser(id, now)
+		} else {
+			r.s.purgeUser(id)
+		}
+	}
+	if anonymize {
+		result.Anonymized = len(userIDs)
+	} else {
+		result.Users = len(userIDs)
+	}
+
+	n := len(r.s.tokens)
+	recent := time.Now().Add(-models.OTPFailureWindow)
+	r.s.tokens = slices.DeleteFunc(r.s.tokens, func(t *token) bool {
+		return (t.expiresAt.Before(cutoff) || (t.consumedAt != nil && t.consumedAt.Before(cutoff))) && t.createdAt.Before(recent)
+	})
+	result.Tokens = n - len(r.s.tokens)
+	return result, nil
+}
+
+// forgetAuditIPs blanks the IP address on the user's audit events, as Purge
+// does in package models. Callers hold s.mu.
+func (s *Store) forgetAuditIPs(id int) {
+	for i := range s.auditEvents {
+		if a := s.auditEvents[i].ActorID; a != nil && *a == id {
+			s.auditEvents[i].IP = ""
+		}
+	}
+}
+
+// anonymizeUser mirrors anonymizeUsers in package models. Callers hold s.mu.
+func (s *Store) anonymizeUser(id int, now time.Time) {
+	for key := range s.bookmarks {
+		if key.userID == id {
+			delete(s.bookmarks, key)
+		}
+	}
+	s.deleteAPITokens(id)
+	s.tokens = slices.DeleteFunc(s.tokens, func(t *token) bool { return t.userID == id })
+
+	rec := s.users[id]
+	rec.user.StudentNumber = ""
+	rec.user.FirstName = "Deleted"
+	rec.user.LastName = "User"
+	rec.user.Email = fmt.Sprintf("deleted-%d@invalid", id)
+	rec.user.EmailVerified = false
+	rec.user.College = ""
+	rec.user.Course = ""
+	rec.user.IsModerator = false
+	rec.user.UpdatedAt = now
+	rec.privacy = models.PrivacySettings{HideActivity: true}
+	rec.passwordHash = ""
+	rec.anonymizedAt = &now
+}
+
+// purgeUser hard-deletes a user along with the rows that cascade from them.
+// Their materials must already be gone. Callers hold s.mu.
+func (s *Store) purgeUser(id int) {
+	delete(s.users, id)
+	delete(s.userBadges, id)
+	for key := range s.votes {
+		if key.userID == id {
+			delete(s.votes, key)
+		}
+	}
+	for key := range s.bookmarks {
+		if key.userID == id {
+			delete(s.bookmarks, key)
+		}
+	}
+	s.pointEvents = slices.DeleteFunc(s.pointEvents, func(e pointEvent) bool { return e.userID == id })
+	s.deleteComments(func(c *commentRecord) bool { return c.comment.AuthorID == id })
+	for _, c := range s.comments {
+		c.mentions = slices.DeleteFunc(c.mentions, func(userID int) bool { return userID == id })
+	}
+	for reportID, rec := range s.reports {
+		if rec.reporterID == id {
+			delete(s.reports, reportID)
+		} else if h := rec.report.HandledBy; h != nil && *h == id {
+			rec.report.HandledBy = nil
+		}
+	}
+	s.deleteAPITokens(id)
+	s.tokens = slices.DeleteFunc(s.tokens, func(t *token) bool { return t.userID == id })
+}
@@ -0,0 +1,339 @@
+// Package memory is an in-memory implementation of the model repositories.
+// It mirrors the Postgres models closely enough to stand in for them in
+// handler tests, without a database.
+package memory
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+// ErrDuplicate mirrors a unique constraint violation.
+var ErrDuplicate = errors.New("duplicate key value")
+
+type userRecord struct {
+	user         models.User
+	passwordHash string
+	privacy      models.PrivacySettings
+	deletedAt    *time.Time
+	anonymizedAt *time.Time
+}
+
+type materialRecord struct {
+	material  models.Material
+	hiddenAt  *time.Time
+	deletedAt *time.Time
+}
+
+type pointEvent struct {
+	userID     int
+	points     int
+	reason     string
+	materialID *int
+	createdAt  time.Time
+}
+
+// token is a row of user_tokens. hash is what callers pass in; the store
+// never sees the token itself.
+type token struct {
+	userID     int
+	purpose    string
+	hash       string
+	newEmail   string
+	attempts   int
+	expiresAt  time.Time
+	consumedAt *time.Time
+	createdAt  time.Time
+}
+
+// live reports whether t can still be consumed at now.
+func (t *token) live(now time.Time) bool {
+	return t.consumedAt == nil && t.expiresAt.After(now)
+}
+
+type pair struct {
+	materialID int
+	userID     int
+}
+
+type vote struct {
+	voteType  string
+	createdAt time.Time
+}
+
+type commentRecord struct {
+	comment   models.Comment
+	mentions  []int
+	edits     []models.CommentEdit
+	editedAt  *time.Time
+	deletedAt *time.Time
+}
+
+type reportRecord struct {
+	report     models.Report
+	reporterID int
+}
+
+type apiTokenRecord struct {
+	token models.APIToken
+	hash  string
+}
+
+// Store holds every table. Users and Materials return repositories sharing
+// it, so cross-entity behavior such as point reversal on material delete
+// matches Postgres.
+type Store struct {
+	mu sync.Mutex
+
+	nextUserID     int
+	nextMaterialID int
+	nextCommentID  int
+	nextReportID   int
+	nextAuditID    int64
+	nextAPITokenID int
+
+	users       map[int]*userRecord
+	materials   map[int]*materialRecord
+	votes       map[pair]vote
+	bookmarks   map[pair]time.Time
+	badges      []models.Badge
+	userBadges  map[int]map[int]time.Time
+	pointEvents []pointEvent
+	tokens      []*token
+	comments    map[int]*commentRecord
+	reports     map[int]*reportRecord
+	auditEvents []models.AuditEvent
+	apiTokens   []*apiTokenRecord
+}
+
+// NewStore returns an empty store seeded with the same badges as the initial
+// migration.
+func NewStore() *Store {
+	return &Store{
+		nextUserID:     1,
+		nextMaterialID: 1,
+		nextCommentID:  1,
+		nextReportID:   1,
+		nextAuditID:    1,
+		nextAPITokenID: 1,
+		users:          map[int]*userRecord{},
+		materials:      map[int]*materialRecord{},
+		votes:          map[pair]vote{},
+		bookmarks:      map[pair]time.Time{},
+		badges: []models.Badge{
+			{ID: 1, Name: "Freshie", Description: "First contribution!", ImageURL: "/badges/freshie.png", PointsRequired: 0},
+			{ID: 2, Name: "Scholar", Description: "Solid contributor!", ImageURL: "/badges/scholar.png", PointsRequired: 50},
+			{ID: 3, Name: "Elite", Description: "Top-tier contributor!", ImageURL: "/badges/elite.png", PointsRequired: 100},
+		},
+		userBadges: map[int]map[int]time.Time{},
+		comments:   map[int]*commentRecord{},
+		reports:    map[int]*reportRecord{},
+	}
+}
+
+func (s *Store) Users() models.UserRepository {
+	return &UserRepository{s: s}
+}
+
+func (s *Store) Materials() models.MaterialRepository {
+	return &MaterialRepository{s: s}
+}
+
+func (s *Store) Comments() models.CommentRepository {
+	return &CommentRepository{s: s}
+}
+
+func (s *Store) Reports() models.ReportRepository {
+	return &ReportRepository{s: s}
+}
+
+func (s *Store) Audit() models.AuditRepository {
+	return &AuditRepository{s: s}
+}
+
+func (s *Store) APITokens() models.APITokenRepository {
+	return &APITokenRepository{s: s}
+}
+
+func (s *Store) Exports() models.ExportRepository {
+	return &ExportRepository{s: s}
+}
+
+func (s *Store) Retention() models.RetentionRepository {
+	return &RetentionRepository{s: s}
+}
+
+// Repositories returns every repository backed by the store.
+func (s *Store) Repositories() models.Repositories {
+	return models.Repositories{
+		Users:     s.Users(),
+		Materials: s.Materials(),
+		Comments:  s.Comments(),
+		Reports:   s.Reports(),
+		Audit:     s.Audit(),
+		Tokens:    s.APITokens(),
+		Exports:   s.Exports(),
+		Retention: s.Retention(),
+	}
+}
+
+// liveUser returns a user that has not been soft-deleted. Callers hold s.mu.
+func (s *Store) liveUser(id int) (*userRecord, bool) {
+	rec, ok := s.users[id]
+	if !ok || rec.deletedAt != nil {
+		return nil, false
+	}
+	return rec, true
+}
+
+// uploaderLive mirrors models.uploaderLive: the user is active or was
+// anonymized when their account was purged. Callers hold s.mu.
+func (s *Store) uploaderLive(id int) bool {
+	rec, ok := s.users[id]
+	return ok && (rec.deletedAt == nil || rec.anonymizedAt != nil)
+}
+
+// findToken returns the newest live token matching match. Callers hold s.mu.
+func (s *Store) findToken(match func(*token) bool, now time.Time) *token {
+	for i := len(s.tokens) - 1; i >= 0; i-- {
+		if t := s.tokens[i]; t.live(now) && match(t) {
+			return t
+		}
+	}
+	return nil
+}
+
+// revokeTokens consumes the user's live tokens for purposes. Callers hold
+// s.mu.
+func (s *Store) revokeTokens(userID int, now time.Time, purposes ...string) {
+	for _, t := range s.tokens {
+		if t.userID == userID && t.consumedAt == nil && slices.Contains(purposes, t.purpose) {
+			t.consumedAt = &now
+		}
+	}
+}
+
+// otpFailures counts the user's wrong guesses on OTPs issued within
+// models.OTPFailureWindow of now. Callers hold s.mu.
+func (s *Store) otpFailures(userID int, now time.Time) int {
+	n := 0
+	for _, t := range s.tokens {
+		if t.userID == userID && t.purpose == models.TokenPasswordOTP && t.createdAt.After(now.Add(-models.OTPFailureWindow)) {
+			n += t.attempts
+		}
+	}
+	return n
+}
+
+// addPoints records a point event and updates the user's total. Callers hold
+// s.mu.
+func (s *Store) addPoints(userID, points int, reason string, materialID *int, now time.Time) {
+	if rec, ok := s.users[userID]; ok {
+		rec.user.Points += points
+		rec.user.UpdatedAt = now
+	}
+	var mid *int
+	if materialID != nil {
+		id := *materialID
+		mid = &id
+	}
+	s.pointEvents = append(s.pointEvents, pointEvent{userID: userID, points: points, reason: reason, materialID: mid, createdAt: now})
+}
+
+// awardPoints adds points and grants any badges the new total unlocks.
+// Callers hold s.mu.
+func (s *Store) awardPoints(userID, points int, reason string, materialID *int, now time.Time) {
+	s.addPoints(userID, points, reason, materialID, now)
+
+	awarded := s.userBadges[userID]
+	if awarded == nil {
+		awarded = map[int]time.Time{}
+		s.userBadges[userID] = awarded
+	}
+	for _, b := range s.badges {
+		if _, ok := awarded[b.ID]; !ok && b.PointsRequired <= s.users[userID].user.Points {
+			awarded[b.ID] = now
+		}
+	}
+}
+
+// deleteComments removes the matching comments and, as ON DELETE CASCADE
+// would, every reply beneath them. Callers hold s.mu.
+func (s *Store) deleteComments(match func(*commentRecord) bool) {
+	for id, rec := range s.comments {
+		if match(rec) {
+			delete(s.comments, id)
+		}
+	}
+	for removed := true; removed; {
+		removed = false
+		for id, rec := range s.comments {
+			if p := rec.comment.ParentID; p != nil && s.comments[*p] == nil {
+				delete(s.comments, id)
+				removed = true
+			}
+		}
+	}
+}
+
+// purgeMaterial hard-deletes a material along with the rows that cascade
+// from it. Reports and point events keep their rows but lose the reference.
+// Callers hold s.mu.
+func (s *Store) purgeMaterial(id int) {
+	delete(s.materials, id)
+	for key := range s.votes {
+		if key.materialID == id {
+			delete(s.votes, key)
+		}
+	}
+	for key := range s.bookmarks {
+		if key.materialID == id {
+			delete(s.bookmarks, key)
+		}
+	}
+	s.deleteComments(func(c *commentRecord) bool { return c.comment.MaterialID == id })
+	for _, rec := range s.reports {
+		if m := rec.report.MaterialID; m != nil && *m == id {
+			rec.report.MaterialID = nil
+		}
+	}
+	for i, e := range s.pointEvents {
+		if e.materialID != nil && *e.materialID == id {
+			s.pointEvents[i].materialID = nil
+		}
+	}
+}
+
+func (s *Store) uploaderSummary(uploaderID int) *models.UploaderSummary {
+	rec := s.users[uploaderID]
+	return &models.UploaderSummary{
+		ID:          uploaderID,
+		DisplayName: models.DisplayName(rec.user.FirstName, rec.user.LastName, rec.privacy.Handle, rec.privacy.HideRealName),
+	}
+}
+
+// page applies LIMIT and OFFSET to items. Like limitArg in package models, a
+// zero limit keeps every item.
+func page[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// pageOf is page for results that are returned as is, which must never be
+// nil.
+func pageOf[T any](items []T, limit, offset int) []T {
+	if items = page(items, limit, offset); items == nil {
+		return []T{}
+	}
+	return items
+}
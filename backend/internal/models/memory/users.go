@@ -0,0 +1,459 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+type UserRepository struct {
+	s *Store
+}
+
+var _ models.UserRepository = (*UserRepository)(nil)
+
+func copyUser(rec *userRecord) *models.User {
+	u := rec.user
+	return &u
+}
+
+func (r *UserRepository) Register(ctx context.Context, user *models.User, passwordHash, verificationHash string, tokenExpiresAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, rec := range r.s.users {
+		if rec.user.Email == user.Email || (user.StudentNumber != "" && rec.user.StudentNumber == user.StudentNumber) {
+			return ErrDuplicate
+		}
+	}
+	user.ID = r.s.nextUserID
+	r.s.nextUserID++
+	r.s.users[user.ID] = &userRecord{user: *user, passwordHash: passwordHash}
+	r.s.tokens = append(r.s.tokens, &token{userID: user.ID, purpose: models.TokenEmailVerify, hash: verificationHash, expiresAt: tokenExpiresAt, createdAt: time.Now()})
+	return nil
+}
+
+func (r *UserRepository) find(match func(*userRecord) bool) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, rec := range r.s.users {
+		if rec.deletedAt == nil && match(rec) {
+			return copyUser(rec), nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	return r.find(func(rec *userRecord) bool { return rec.user.ID == id })
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.find(func(rec *userRecord) bool { return rec.user.Email == email })
+}
+
+func (r *UserRepository) GetByStudentNumber(ctx context.Context, studentNumber string) (*models.User, error) {
+	return r.find(func(rec *userRecord) bool { return rec.user.StudentNumber == studentNumber })
+}
+
+func (r *UserRepository) GetByHandles(ctx context.Context, handles []string) ([]*models.User, error) {
+	wanted := map[string]bool{}
+	for _, h := range handles {
+		wanted[strings.ToLower(h)] = true
+	}
+	return r.list(func(rec *userRecord) bool {
+		return rec.deletedAt == nil && rec.privacy.Handle != "" && wanted[strings.ToLower(rec.privacy.Handle)]
+	}, byCreatedDesc), nil
+}
+
+func (r *UserRepository) GetAll(ctx context.Context, limit, offset int) ([]*models.User, int, error) {
+	users := r.list(func(rec *userRecord) bool { return rec.deletedAt == nil }, byCreatedDesc)
+	return pageOf(users, limit, offset), len(users), nil
+}
+
+func (r *UserRepository) GetDeleted(ctx context.Context, limit, offset int) ([]*models.User, int, error) {
+	r.s.mu.Lock()
+	deletedAt := map[int]time.Time{}
+	for id, rec := range r.s.users {
+		if rec.deletedAt != nil {
+			deletedAt[id] = *rec.deletedAt
+		}
+	}
+	r.s.mu.Unlock()
+
+	users := r.list(func(rec *userRecord) bool { return rec.deletedAt != nil && rec.anonymizedAt == nil }, func(a, b *models.User) bool {
+		if !deletedAt[a.ID].Equal(deletedAt[b.ID]) {
+			return deletedAt[a.ID].After(deletedAt[b.ID])
+		}
+		return a.ID > b.ID
+	})
+	return pageOf(users, limit, offset), len(users), nil
+}
+
+func byCreatedDesc(a, b *models.User) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID > b.ID
+}
+
+func (r *UserRepository) list(match func(*userRecord) bool, less func(a, b *models.User) bool) []*models.User {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	users := []*models.User{}
+	for _, rec := range r.s.users {
+		if match(rec) {
+			users = append(users, copyUser(rec))
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return less(users[i], users[j]) })
+	return users
+}
+
+func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	rec, ok := r.s.liveUser(user.ID)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	rec.user.FirstName = user.FirstName
+	rec.user.LastName = user.LastName
+	rec.user.College = user.College
+	rec.user.Course = user.Course
+	rec.user.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	rec, ok := r.s.liveUser(id)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	now := time.Now()
+	rec.deletedAt = &now
+	return nil
+}
+
+func (r *UserRepository) Restore(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	rec, ok := r.s.users[id]
+	if !ok || rec.deletedAt == nil {
+		return sql.ErrNoRows
+	}
+	rec.deletedAt = nil
+	rec.user.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *UserRepository) SetModerator(ctx context.Context, userID int, isModerator bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	rec, ok := r.s.users[userID]
+	if !ok || !rec.user.IsStudent {
+		return sql.ErrNoRows
+	}
+	if rec.user.IsModerator != isModerator {
+		rec.user.SessionVersion++
+	}
+	rec.user.IsModerator = isModerator
+	rec.user.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *UserRepository) IncrementPointsAndCheckBadges(ctx context.Context, userID, points int, reason string, materialID *int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.users[userID]; !ok {
+		return sql.ErrNoRows
+	}
+	r.s.awardPoints(userID, points, reason, materialID, time.Now())
+	return nil
+}
+
+func (r *UserRepository) GetLeaderboard(ctx context.Context, filter models.LeaderboardFilter, viewerID int) ([]*models.LeaderboardEntry, *models.LeaderboardEntry, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	totals := map[int]int{}
+	for _, e := range r.s.pointEvents {
+		if filter.Since.IsZero() || !e.createdAt.Before(filter.Since) {
+			totals[e.userID] += e.points
+		}
+	}
+
+	var ranked []*models.LeaderboardEntry
+	for id, rec := range r.s.users {
+		u := rec.user
+		if !u.IsStudent || rec.deletedAt != nil {
+			continue
+		}
+		if (filter.College != "" && u.College != filter.College) || (filter.Course != "" && u.Course != filter.Course) {
+			continue
+		}
+		ranked = append(ranked, &models.LeaderboardEntry{
+			UserID:      id,
+			DisplayName: models.DisplayName(u.FirstName, u.LastName, rec.privacy.Handle, rec.privacy.HideRealName),
+			College:     u.College,
+			Course:      u.Course,
+			Points:      totals[id],
+		})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Points != ranked[j].Points {
+			return ranked[i].Points > ranked[j].Points
+		}
+		return ranked[i].UserID < ranked[j].UserID
+	})
+
+	var entries []*models.LeaderboardEntry
+	var viewer *models.LeaderboardEntry
+	for i, e := range ranked {
+		// RANK(): ties share the rank of the first tied position.
+		e.Rank = i + 1
+		if i > 0 && ranked[i-1].Points == e.Points {
+			e.Rank = ranked[i-1].Rank
+		}
+		if i < filter.Limit {
+			entries = append(entries, e)
+		}
+		if e.UserID == viewerID {
+			viewer = e
+		}
+	}
+	return entries, viewer, nil
+}
+
+func (r *UserRepository) GetBadges(ctx context.Context, userID int) ([]*models.Badge, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	badges := []*models.Badge{}
+	for _, b := range r.s.badges {
+		if at, ok := r.s.userBadges[userID][b.ID]; ok {
+			badge := b
+			badge.AwardedAt = at
+			badges = append(badges, &badge)
+		}
+	}
+	sort.SliceStable(badges, func(i, j int) bool { return badges[i].AwardedAt.Before(badges[j].AwardedAt) })
+	return badges, nil
+}
+
+func (r *UserRepository) GetPublicProfile(ctx context.Context, userID int) (*models.PublicProfile, error) {
+	r.s.mu.Lock()
+	rec, ok := r.s.liveUser(userID)
+	if !ok {
+		r.s.mu.Unlock()
+		return nil, sql.ErrNoRows
+	}
+	p := &models.PublicProfile{
+		ID:           rec.user.ID,
+		DisplayName:  models.DisplayName(rec.user.FirstName, rec.user.LastName, rec.privacy.Handle, rec.privacy.HideRealName),
+		College:      rec.user.College,
+		Course:       rec.user.Course,
+		Points:       rec.user.Points,
+		MemberSince:  rec.user.CreatedAt,
+		HideActivity: rec.privacy.HideActivity,
+	}
+	r.s.mu.Unlock()
+
+	var err error
+	if p.Badges, err = r.GetBadges(ctx, userID); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (r *UserRepository) GetPrivacySettings(ctx context.Context, userID int) (*models.PrivacySettings, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	rec, ok := r.s.users[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	settings := rec.privacy
+	return &settings, nil
+}
+
+func (r *UserRepository) UpdatePrivacySettings(ctx context.Context, userID int, s *models.PrivacySettings) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if s.Handle != "" {
+		for id, rec := range r.s.users {
+			if id != userID && strings.EqualFold(rec.privacy.Handle, s.Handle) {
+				return models.ErrHandleTaken
+			}
+		}
+	}
+	if rec, ok := r.s.users[userID]; ok {
+		rec.privacy = *s
+		rec.user.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (r *UserRepository) IsHandleTaken(ctx context.Context, handle string, excludeUserID int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for id, rec := range r.s.users {
+		if id != excludeUserID && rec.privacy.Handle != "" && strings.EqualFold(rec.privacy.Handle, handle) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *UserRepository) GetPasswordHash(ctx context.Context, userID int) (string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	rec, ok := r.s.users[userID]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return rec.passwordHash, nil
+}
+
+func (r *UserRepository) SessionVersion(ctx context.Context, userID int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	rec, ok := r.s.liveUser(userID)
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return rec.user.SessionVersion, nil
+}
+
+func (r *UserRepository) ChangePassword(ctx context.Context, userID int, hash string) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	rec, ok := r.s.users[userID]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	r.s.setPassword(rec, hash, time.Now())
+	return rec.user.SessionVersion, nil
+}
+
+// setPassword mirrors setPassword in package models. Callers hold s.mu.
+func (s *Store) setPassword(rec *userRecord, hash string, now time.Time) {
+	rec.passwordHash = hash
+	rec.user.SessionVersion++
+	rec.user.UpdatedAt = now
+	s.deleteAPITokens(rec.user.ID)
+}
+
+func (r *UserRepository) VerifyEmail(ctx context.Context, hash string) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	t := r.s.findToken(func(t *token) bool { return t.purpose == models.TokenEmailVerify && t.hash == hash }, now)
+	if t == nil {
+		return 0, sql.ErrNoRows
+	}
+	rec, ok := r.s.liveUser(t.userID)
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	t.consumedAt = &now
+	rec.user.EmailVerified = true
+	rec.user.UpdatedAt = now
+	return t.userID, nil
+}
+
+func (r *UserRepository) RequestEmailChange(ctx context.Context, userID int, newEmail, hash string, expiresAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.revokeTokens(userID, time.Now(), models.TokenEmailChange)
+	r.s.tokens = append(r.s.tokens, &token{userID: userID, purpose: models.TokenEmailChange, hash: hash, newEmail: newEmail, expiresAt: expiresAt, createdAt: time.Now()})
+	return nil
+}
+
+func (r *UserRepository) ConfirmEmailChange(ctx context.Context, hash string) (int, string, string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	t := r.s.findToken(func(t *token) bool { return t.purpose == models.TokenEmailChange && t.hash == hash }, now)
+	if t == nil {
+		return 0, "", "", sql.ErrNoRows
+	}
+	rec, ok := r.s.liveUser(t.userID)
+	if !ok {
+		return 0, "", "", sql.ErrNoRows
+	}
+	for id, other := range r.s.users {
+		if id != t.userID && other.user.Email == t.newEmail {
+			return 0, "", "", models.ErrEmailTaken
+		}
+	}
+	t.consumedAt = &now
+	oldEmail := rec.user.Email
+	rec.user.Email = t.newEmail
+	rec.user.EmailVerified = true
+	rec.user.UpdatedAt = now
+	return t.userID, oldEmail, t.newEmail, nil
+}
+
+func (r *UserRepository) StoreOTP(ctx context.Context, userID int, hash string, expiresAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	if r.s.otpFailures(userID, now) >= models.MaxOTPFailures {
+		return models.ErrTooManyAttempts
+	}
+	r.s.revokeTokens(userID, now, models.TokenPasswordOTP, models.TokenPasswordReset)
+	r.s.tokens = append(r.s.tokens, &token{userID: userID, purpose: models.TokenPasswordOTP, hash: hash, expiresAt: expiresAt, createdAt: now})
+	return nil
+}
+
+func (r *UserRepository) ExchangeOTP(ctx context.Context, userID int, otpHash, resetHash string, expiresAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	t := r.s.findToken(func(t *token) bool { return t.userID == userID && t.purpose == models.TokenPasswordOTP }, now)
+	if t == nil {
+		return sql.ErrNoRows
+	}
+	failures := r.s.otpFailures(userID, now)
+	if failures >= models.MaxOTPFailures {
+		t.consumedAt = &now
+		return models.ErrTooManyAttempts
+	}
+	if t.hash != otpHash {
+		t.attempts++
+		if t.attempts >= models.MaxOTPAttempts || failures+1 >= models.MaxOTPFailures {
+			t.consumedAt = &now
+			return models.ErrTooManyAttempts
+		}
+		return sql.ErrNoRows
+	}
+	t.consumedAt = &now
+	r.s.revokeTokens(userID, now, models.TokenPasswordReset)
+	r.s.tokens = append(r.s.tokens, &token{userID: userID, purpose: models.TokenPasswordReset, hash: resetHash, expiresAt: expiresAt, createdAt: now})
+	return nil
+}
+
+func (r *UserRepository) ResetPassword(ctx context.Context, userID int, resetHash, passwordHash string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	t := r.s.findToken(func(t *token) bool {
+		return t.userID == userID && t.purpose == models.TokenPasswordReset && t.hash == resetHash
+	}, now)
+	if t == nil {
+		return sql.ErrNoRows
+	}
+	rec, ok := r.s.users[userID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	t.consumedAt = &now
+	r.s.setPassword(rec, passwordHash, now)
+	return nil
+}
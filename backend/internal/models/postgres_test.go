@@ -0,0 +1,39 @@
+package models_test
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/database"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+	"github.com/ISKOnnect/iskonnect-web/internal/models/repotest"
+	_ "github.com/lib/pq"
+)
+
+// TestContract migrates the database at TEST_DATABASE_URL and runs the
+// repository contract against it. Every table but badges is emptied before
+// each test, so never point it at a database you care about.
+func TestContract(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.Migrate(context.Background(), db, slog.Default()); err != nil {
+		t.Fatal(err)
+	}
+
+	repotest.Run(t, func(t *testing.T) models.Repositories {
+		if _, err := db.Exec("TRUNCATE user_credentials, audit_events RESTART IDENTITY CASCADE"); err != nil {
+			t.Fatalf("emptying tables: %v", err)
+		}
+		return models.NewRepositories(db, models.QueryOptions{})
+	})
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrHandleTaken is returned by UpdatePrivacySettings when another user
+// holds the handle in any letter case.
+var ErrHandleTaken = errors.New("handle already taken")
+
+type PrivacySettings struct {
+	Handle       string `json:"handle"`
+	HideRealName bool   `json:"hide_real_name"`
+	HideActivity bool   `json:"hide_activity"`
+}
+
+type Badge struct {
+	ID             int       `json:"id"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	ImageURL       string    `json:"image_url"`
+	PointsRequired int       `json:"points_required"`
+	AwardedAt      time.Time `json:"awarded_at"`
+}
+
+// UploaderSummary is the compact uploader view embedded in material responses.
+type UploaderSummary struct {
+	ID          int    `json:"id"`
+	DisplayName string `json:"display_name"`
+}
+
+// PublicProfile is what other users see at /users/{id}/profile. Uploads is
+// left empty when the owner hides their activity.
+type PublicProfile struct {
+	ID          int         `json:"id"`
+	DisplayName string      `json:"display_name"`
+	College     string      `json:"college,omitempty"`
+	Course      string      `json:"course,omitempty"`
+	Points      int         `json:"points"`
+	Badges      []*Badge    `json:"badges"`
+	Uploads     []*Material `json:"uploads,omitempty"`
+	MemberSince time.Time   `json:"member_since"`
+
+	HideActivity bool `json:"-"`
+}
+
+// DisplayName returns the handle when the user hides their real name and
+// their full name otherwise.
+func DisplayName(firstName, lastName, handle string, hideRealName bool) string {
+	if hideRealName && handle != "" {
+		return handle
+	}
+	return strings.TrimSpace(firstName + " " + lastName)
+}
+
+func (m *UserModel) GetPrivacySettings(ctx context.Context, userID int) (*PrivacySettings, error) {
+	ctx, done := m.opts.begin(ctx, "UserModel.GetPrivacySettings")
+	defer done()
+	var s PrivacySettings
+	err := m.db.QueryRowContext(ctx, "SELECT COALESCE(handle, ''), hide_real_name, hide_activity FROM users WHERE id = $1", userID).Scan(&s.Handle, &s.HideRealName, &s.HideActivity)
+	if err == sql.ErrNoRows {
+		return nil, sql.ErrNoRows
+	}
+	return &s, err
+}
+
+func (m *UserModel) UpdatePrivacySettings(ctx context.Context, userID int, s *PrivacySettings) error {
+	ctx, done := m.opts.begin(ctx, "UserModel.UpdatePrivacySettings")
+	defer done()
+	query := `
+		UPDATE users SET handle = NULLIF($1, ''), hide_real_name = $2, hide_activity = $3, updated_at = $4
+		WHERE id = $5
+	`
+	_, err := m.db.ExecContext(ctx, query, s.Handle, s.HideRealName, s.HideActivity, time.Now(), userID)
+	if isUniqueViolation(err) {
+		return ErrHandleTaken
+	}
+	return err
+}
+
+func (m *UserModel) IsHandleTaken(ctx context.Context, handle string, excludeUserID int) (bool, error) {
+	ctx, done := m.opts.begin(ctx, "UserModel.IsHandleTaken")
+	defer done()
+	var exists bool
+	err := m.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(handle) = LOWER($1) AND id <> $2)", handle, excludeUserID).Scan(&exists)
+	return exists, err
+}
+
+// GetByHandles returns the users owning any of the given handles, matched
+// case-insensitively. Unknown handles are ignored.
+func (m *UserModel) GetByHandles(ctx context.Context, handles []string) ([]*User, error) {
+	ctx, done := m.opts.begin(ctx, "UserModel.GetByHandles")
+	defer done()
+	lowered := make([]string, len(handles))
+	for i, h := range handles {
+		lowered[i] = strings.ToLower(h)
+	}
+	query := `
+		SELECT id, student_number, first_name, last_name, email, COALESCE(college, ''), COALESCE(course, ''), is_student, is_moderator, points, email_verified, created_at, updated_at
+		FROM users WHERE LOWER(handle) = ANY($1) AND deleted_at IS NULL
+	`
+	rows, err := m.db.QueryContext(ctx, query, pq.Array(lowered))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.StudentNumber, &u.FirstName, &u.LastName, &u.Email, &u.College, &u.Course, &u.IsStudent, &u.IsModerator, &u.Points, &u.EmailVerified, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, &u)
+	}
+	return users, rows.Err()
+}
+
+func (m *UserModel) GetBadges(ctx context.Context, userID int) ([]*Badge, error) {
+	ctx, done := m.opts.begin(ctx, "UserModel.GetBadges")
+	defer done()
+	query := `
+		SELECT b.id, b.name, b.description, b.image_url, b.points_required, ub.awarded_at
+		FROM user_badges ub
+		JOIN badges b ON b.id = ub.badge_id
+		WHERE ub.user_id = $1
+		ORDER BY ub.awarded_at
+	`
+	rows, err := m.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	badges := []*Badge{}
+	for rows.Next() {
+		var b Badge
+		if err := rows.Scan(&b.ID, &b.Name, &b.Description, &b.ImageURL, &b.PointsRequired, &b.AwardedAt); err != nil {
+			return nil, err
+		}
+		badges = append(badges, &b)
+	}
+	return badges, rows.Err()
+}
+
+// GetPublicProfile loads the public view of a user together with their
+// badges. Callers fill Uploads unless HideActivity is set.
+func (m *UserModel) GetPublicProfile(ctx context.Context, userID int) (*PublicProfile, error) {
+	ctx, done := m.opts.begin(ctx, "UserModel.GetPublicProfile")
+	defer done()
+	query := `
+		SELECT id, first_name, last_name, COALESCE(handle, ''), hide_real_name, hide_activity,
+		       COALESCE(college, ''), COALESCE(course, ''), points, created_at
+		FROM users WHERE id = $1 AND deleted_at IS NULL
+	`
+	var p PublicProfile
+	var firstName, lastName, handle string
+	var hideRealName bool
+	err := m.db.QueryRowContext(ctx, query, userID).Scan(&p.ID, &firstName, &lastName, &handle, &hideRealName, &p.HideActivity, &p.College, &p.Course, &p.Points, &p.MemberSince)
+	if err == sql.ErrNoRows {
+		return nil, sql.ErrNoRows
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.DisplayName = DisplayName(firstName, lastName, handle, hideRealName)
+
+	if p.Badges, err = m.GetBadges(ctx, userID); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/ISKOnnect/iskonnect-web/internal/models")
+
+// QueryOptions bounds how long a single model call may spend in the
+// database and sets the duration past which it is logged as slow. Zero
+// values disable the timeout and the slow-query log respectively. Slow
+// queries go to Logger, or the default logger when it is nil.
+type QueryOptions struct {
+	Timeout       time.Duration
+	SlowThreshold time.Duration
+	Logger        *slog.Logger
+}
+
+// begin derives the context for one model call from the caller's, usually
+// the request context, so a client disconnect or server shutdown cancels the
+// query. Each call gets its own span. The returned func must be deferred; it
+// ends the span, releases the timeout and logs the call if it was slow.
+func (o QueryOptions) begin(ctx context.Context, op string) (context.Context, func()) {
+	ctx, span := tracer.Start(ctx, op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "postgresql")),
+	)
+	start := time.Now()
+	cancel := context.CancelFunc(func() {})
+	if o.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+	}
+	return ctx, func() {
+		cancel()
+		span.End()
+		elapsed := time.Since(start)
+		if o.SlowThreshold > 0 && elapsed >= o.SlowThreshold {
+			logger := o.Logger
+			if logger == nil {
+				logger = slog.Default()
+			}
+			logger.WarnContext(ctx, "slow query", "op", op, "duration", elapsed)
+		}
+	}
+}
+
+// isUniqueViolation reports whether err is Postgres rejecting a duplicate
+// key.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// limitArg binds a LIMIT placeholder. Zero binds NULL, which Postgres reads
+// as no limit, for callers that want every row.
+func limitArg(limit int) interface{} {
+	if limit <= 0 {
+		return nil
+	}
+	return limit
+}
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	ReportOpen      = "open"
+	ReportReviewing = "reviewing"
+	ReportResolved  = "resolved"
+	ReportDismissed = "dismissed"
+
+	ModerationHide    = "hide"
+	ModerationRestore = "restore"
+	ModerationDelete  = "delete"
+	ModerationDismiss = "dismiss"
+)
+
+var ReportReasons = []string{"copyright", "wrong_subject", "spam", "inappropriate", "other"}
+
+// ErrMaterialGone is returned when a moderation action targets a report
+// whose material has already been purged.
+var ErrMaterialGone = errors.New("reported material no longer exists")
+
+type Report struct {
+	ID             int              `json:"id"`
+	MaterialID     *int             `json:"material_id"`
+	MaterialTitle  string           `json:"material_title,omitempty"`
+	MaterialHidden bool             `json:"material_hidden"`
+	Reporter       *UploaderSummary `json:"reporter"`
+	Reason         string           `json:"reason"`
+	Details        string           `json:"details"`
+	Status         string           `json:"status"`
+	Resolution     string           `json:"resolution,omitempty"`
+	HandledBy      *int             `json:"handled_by,omitempty"`
+	HandledAt      *time.Time       `json:"handled_at,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+type ReportFilter struct {
+	Status string
+	Reason string
+	Limit  int
+	Offset int
+}
+
+type ReportModel struct {
+	db   *sql.DB
+	opts QueryOptions
+}
+
+func NewReportModel(db *sql.DB, opts QueryOptions) *ReportModel {
+	return &ReportModel{db: db, opts: opts}
+}
+
+func IsValidReportReason(reason string) bool {
+	for _, r := range ReportReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// Create files a report. A reporter can only have one pending report per
+// material; a duplicate returns sql.ErrNoRows.
+func (m *ReportModel) Create(ctx context.Context, materialID, reporterID int, reason, details string) (int, error) {
+	ctx, done := m.opts.begin(ctx, "ReportModel.Create")
+	defer done()
+	query := `
+		INSERT INTO material_reports (material_id, reporter_id, reason, details, status, created_at)
+		VALUES ($1, $2, $3, $4, 'open', $5)
+		ON CONFLICT (material_id, reporter_id) WHERE status IN ('open', 'reviewing') DO NOTHING
+		RETURNING id
+	`
+	var id int
+	err := m.db.QueryRowContext(ctx, query, materialID, reporterID, reason, details, time.Now()).Scan(&id)
+	return id, err
+}
+
+func (m *ReportModel) List(ctx context.Context, filter ReportFilter) ([]*Report, int, error) {
+	ctx, done := m.opts.begin(ctx, "ReportModel.List")
+	defer done()
+	where := `
+		WHERE ($1::text = '' OR r.status = $1)
+		AND ($2::text = '' OR r.reason = $2)
+	`
+	var total int
+	if err := m.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM material_reports r"+where, filter.Status, filter.Reason).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT r.id, r.material_id, COALESCE(mat.title, ''), mat.hidden_at IS NOT NULL,
+		       r.reporter_id, u.first_name, u.last_name, COALESCE(u.handle, ''), u.hide_real_name,
+		       r.reason, r.details, r.status, COALESCE(r.resolution, ''), r.handled_by, r.handled_at, r.created_at
+		FROM material_reports r
+		JOIN users u ON u.id = r.reporter_id
+		LEFT JOIN materials mat ON mat.id = r.material_id
+	` + where + `
+		ORDER BY r.created_at
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := m.db.QueryContext(ctx, query, filter.Status, filter.Reason, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	reports := []*Report{}
+	for rows.Next() {
+		var r Report
+		var materialID, handledBy sql.NullInt64
+		var handledAt sql.NullTime
+		var reporterID int
+		var up uploaderColumns
+		if err := rows.Scan(&r.ID, &materialID, &r.MaterialTitle, &r.MaterialHidden, &reporterID, &up.firstName, &up.lastName, &up.handle, &up.hideRealName, &r.Reason, &r.Details, &r.Status, &r.Resolution, &handledBy, &handledAt, &r.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		if materialID.Valid {
+			id := int(materialID.Int64)
+			r.MaterialID = &id
+		}
+		if handledBy.Valid {
+			id := int(handledBy.Int64)
+			r.HandledBy = &id
+		}
+		if handledAt.Valid {
+			r.HandledAt = &handledAt.Time
+		}
+		r.Reporter = up.summary(reporterID)
+		reports = append(reports, &r)
+	}
+	return reports, total, rows.Err()
+}
+
+// SetStatus moves a report between triage states without acting on the
+// material.
+func (m *ReportModel) SetStatus(ctx context.Context, id int, status string, moderatorID int) error {
+	ctx, done := m.opts.begin(ctx, "ReportModel.SetStatus")
+	defer done()
+	result, err := m.db.ExecContext(ctx, "UPDATE material_reports SET status = $1, handled_by = $2, handled_at = $3 WHERE id = $4", status, moderatorID, time.Now(), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Apply performs a moderation action for a report. Hide, restore and delete
+// act on the reported material and resolve every pending report for it;
+// dismiss closes only the given report.
+func (m *ReportModel) Apply(ctx context.Context, id int, action string, moderatorID int) error {
+	ctx, done := m.opts.begin(ctx, "ReportModel.Apply")
+	defer done()
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var materialID sql.NullInt64
+	if err := tx.QueryRowContext(ctx, "SELECT material_id FROM material_reports WHERE id = $1 FOR UPDATE", id).Scan(&materialID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if action == ModerationDismiss {
+		_, err := tx.ExecContext(ctx, "UPDATE material_reports SET status = $1, resolution = $2, handled_by = $3, handled_at = $4 WHERE id = $5", ReportDismissed, action, moderatorID, now, id)
+		if err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	if !materialID.Valid {
+		return ErrMaterialGone
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE material_reports SET status = $1, resolution = $2, handled_by = $3, handled_at = $4
+		WHERE id = $5 OR (material_id = $6 AND status IN ('open', 'reviewing'))`,
+		ReportResolved, action, moderatorID, now, id, materialID.Int64)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case ModerationHide:
+		_, err = tx.ExecContext(ctx, "UPDATE materials SET hidden_at = COALESCE(hidden_at, $1) WHERE id = $2", now, materialID.Int64)
+	case ModerationRestore:
+		_, err = tx.ExecContext(ctx, "UPDATE materials SET hidden_at = NULL WHERE id = $1", materialID.Int64)
+	case ModerationDelete:
+		err = deleteMaterial(ctx, tx, int(materialID.Int64))
+	default:
+		return errors.New("unknown moderation action")
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
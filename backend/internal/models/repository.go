@@ -0,0 +1,149 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// UserRepository is the storage behind users, their credentials, points and
+// auth tokens. UserModel implements it over Postgres; package memory
+// provides an in-memory implementation for tests. Methods dealing in mailed
+// tokens take the token's hash.
+type UserRepository interface {
+	Register(ctx context.Context, user *User, passwordHash, verificationHash string, tokenExpiresAt time.Time) error
+	GetByID(ctx context.Context, id int) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	GetByStudentNumber(ctx context.Context, studentNumber string) (*User, error)
+	GetByHandles(ctx context.Context, handles []string) ([]*User, error)
+	GetAll(ctx context.Context, limit, offset int) ([]*User, int, error)
+	GetDeleted(ctx context.Context, limit, offset int) ([]*User, int, error)
+	Update(ctx context.Context, user *User) error
+	Delete(ctx context.Context, id int) error
+	Restore(ctx context.Context, id int) error
+	SetModerator(ctx context.Context, userID int, isModerator bool) error
+
+	IncrementPointsAndCheckBadges(ctx context.Context, userID, points int, reason string, materialID *int) error
+	GetLeaderboard(ctx context.Context, filter LeaderboardFilter, viewerID int) ([]*LeaderboardEntry, *LeaderboardEntry, error)
+	GetBadges(ctx context.Context, userID int) ([]*Badge, error)
+
+	GetPublicProfile(ctx context.Context, userID int) (*PublicProfile, error)
+	GetPrivacySettings(ctx context.Context, userID int) (*PrivacySettings, error)
+	UpdatePrivacySettings(ctx context.Context, userID int, s *PrivacySettings) error
+	IsHandleTaken(ctx context.Context, handle string, excludeUserID int) (bool, error)
+
+	GetPasswordHash(ctx context.Context, userID int) (string, error)
+	SessionVersion(ctx context.Context, userID int) (int, error)
+	ChangePassword(ctx context.Context, userID int, hash string) (int, error)
+	VerifyEmail(ctx context.Context, tokenHash string) (int, error)
+	RequestEmailChange(ctx context.Context, userID int, newEmail, tokenHash string, expiresAt time.Time) error
+	ConfirmEmailChange(ctx context.Context, tokenHash string) (userID int, oldEmail, newEmail string, err error)
+	StoreOTP(ctx context.Context, userID int, otpHash string, expiresAt time.Time) error
+	ExchangeOTP(ctx context.Context, userID int, otpHash, resetHash string, expiresAt time.Time) error
+	ResetPassword(ctx context.Context, userID int, resetHash, passwordHash string) error
+}
+
+// MaterialRepository is the storage behind materials, votes and bookmarks.
+type MaterialRepository interface {
+	Create(ctx context.Context, material *Material) error
+	CreateWithPoints(ctx context.Context, material *Material, points int) error
+	GetByID(ctx context.Context, id int) (*Material, error)
+	List(ctx context.Context, limit, offset int) ([]*Material, int, error)
+	ListAll(ctx context.Context, limit, offset int) ([]*Material, int, error)
+	ListDeleted(ctx context.Context, limit, offset int) ([]*Material, int, error)
+	ListByUploader(ctx context.Context, uploaderID int) ([]*Material, error)
+	Update(ctx context.Context, material *Material) error
+	Delete(ctx context.Context, id int) error
+	Restore(ctx context.Context, id int) error
+
+	Vote(ctx context.Context, materialID, userID int, voteType string) error
+	Bookmark(ctx context.Context, materialID, userID int) error
+	GetBookmarks(ctx context.Context, userID, limit, offset int) ([]*Material, int, error)
+}
+
+// CommentRepository is the storage behind comment threads, their mentions
+// and edit history.
+type CommentRepository interface {
+	Create(ctx context.Context, comment *Comment) error
+	GetByID(ctx context.Context, id int) (*Comment, error)
+	ListByMaterial(ctx context.Context, materialID, limit, offset int) ([]*Comment, int, error)
+	ListReplies(ctx context.Context, parentID, limit, offset int) ([]*Comment, int, error)
+	Update(ctx context.Context, id int, body string, mentionIDs []int) error
+	SoftDelete(ctx context.Context, id int) error
+	GetHistory(ctx context.Context, id int) ([]*CommentEdit, error)
+}
+
+// ReportRepository is the storage behind material reports and the
+// moderation actions taken on them.
+type ReportRepository interface {
+	Create(ctx context.Context, materialID, reporterID int, reason, details string) (int, error)
+	List(ctx context.Context, filter ReportFilter) ([]*Report, int, error)
+	SetStatus(ctx context.Context, id int, status string, moderatorID int) error
+	Apply(ctx context.Context, id int, action string, moderatorID int) error
+}
+
+// AuditRepository is the append-only storage behind the audit log.
+type AuditRepository interface {
+	Create(ctx context.Context, e *AuditEvent) error
+	List(ctx context.Context, filter AuditFilter) ([]*AuditEvent, int, error)
+	Each(ctx context.Context, filter AuditFilter, fn func(*AuditEvent) error) error
+}
+
+// APITokenRepository is the storage behind personal access tokens. Methods
+// take the hash of a token's secret, never the secret itself.
+type APITokenRepository interface {
+	Create(ctx context.Context, t *APIToken, hash string) error
+	ListByUser(ctx context.Context, userID int) ([]*APIToken, error)
+	CountByUser(ctx context.Context, userID int) (int, error)
+	Delete(ctx context.Context, userID, id int) error
+	Authenticate(ctx context.Context, hash string) (*TokenGrant, error)
+}
+
+// ExportRepository reads a user's activity for the personal data export.
+type ExportRepository interface {
+	Activity(ctx context.Context, userID int) (*UserActivity, error)
+}
+
+// RetentionRepository permanently removes rows kept past their retention
+// period.
+type RetentionRepository interface {
+	Purge(cutoff time.Time, anonymize bool) (*PurgeResult, error)
+}
+
+// Repositories holds one implementation of every repository, so the API and
+// background jobs can run against Postgres or, in tests, package memory.
+type Repositories struct {
+	Users     UserRepository
+	Materials MaterialRepository
+	Comments  CommentRepository
+	Reports   ReportRepository
+	Audit     AuditRepository
+	Tokens    APITokenRepository
+	Exports   ExportRepository
+	Retention RetentionRepository
+}
+
+// NewRepositories returns the Postgres implementation of every repository.
+func NewRepositories(db *sql.DB, opts QueryOptions) Repositories {
+	return Repositories{
+		Users:     NewUserModel(db, opts),
+		Materials: NewMaterialModel(db, opts),
+		Comments:  NewCommentModel(db, opts),
+		Reports:   NewReportModel(db, opts),
+		Audit:     NewAuditModel(db, opts),
+		Tokens:    NewAPITokenModel(db, opts),
+		Exports:   NewExportModel(db, opts),
+		Retention: NewRetentionModel(db),
+	}
+}
+
+var (
+	_ UserRepository      = (*UserModel)(nil)
+	_ MaterialRepository  = (*MaterialModel)(nil)
+	_ CommentRepository   = (*CommentModel)(nil)
+	_ ReportRepository    = (*ReportModel)(nil)
+	_ AuditRepository     = (*AuditModel)(nil)
+	_ APITokenRepository  = (*APITokenModel)(nil)
+	_ ExportRepository    = (*ExportModel)(nil)
+	_ RetentionRepository = (*RetentionModel)(nil)
+)
@@ -0,0 +1,772 @@
+// Package repotest is the contract every implementation of the model
+// repositories must meet. Package memory runs it against its store and
+// package models against Postgres, so the two cannot drift apart unnoticed.
+package repotest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+// Open returns repositories over empty storage. It is called once per test.
+type Open func(t *testing.T) models.Repositories
+
+// Run runs the whole contract against the repositories open returns.
+func Run(t *testing.T, open Open) {
+	tests := []struct {
+		name string
+		fn   func(*testing.T, models.Repositories)
+	}{
+		{"UserLifecycle", testUserLifecycle},
+		{"UserUniqueness", testUserUniqueness},
+		{"HandlesIgnoreCase", testHandlesIgnoreCase},
+		{"UserTokens", testUserTokens},
+		{"OTPFailureWindow", testOTPFailureWindow},
+		{"ModeratorRevokesSessions", testModeratorRevokesSessions},
+		{"MaterialPoints", testMaterialPoints},
+		{"MaterialVotes", testMaterialVotes},
+		{"ListPages", testListPages},
+		{"Comments", testComments},
+		{"CommentEdits", testCommentEdits},
+		{"Reports", testReports},
+		{"ModerationActions", testModerationActions},
+		{"Audit", testAudit},
+		{"APITokens", testAPITokens},
+		{"Export", testExport},
+		{"PurgeDeletes", testPurgeDeletes},
+		{"PurgeAnonymizes", testPurgeAnonymizes},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) { tt.fn(t, open(t)) })
+	}
+}
+
+var ctx = context.Background()
+
+// newUser registers a verified student numbered n.
+func newUser(t *testing.T, repos models.Repositories, n int) *models.User {
+	t.Helper()
+	now := time.Now()
+	u := &models.User{
+		StudentNumber: fmt.Sprintf("2020%05d", n),
+		FirstName:     "Juan",
+		LastName:      fmt.Sprintf("Dela Cruz %d", n),
+		Email:         fmt.Sprintf("user%d@up.edu.ph", n),
+		IsStudent:     true,
+		EmailVerified: true,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := repos.Users.Register(ctx, u, "hash", fmt.Sprintf("verify-%d", n), now.Add(time.Hour)); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return u
+}
+
+func newMaterial(t *testing.T, repos models.Repositories, uploaderID int, title string) *models.Material {
+	t.Helper()
+	m := &models.Material{
+		Title:       title,
+		Description: "Notes",
+		Subject:     "CMSC 21",
+		College:     "CoE",
+		Course:      "BSCS",
+		FileURL:     "https://files.example.com/" + title + ".pdf",
+		Filename:    title + ".pdf",
+		UploaderID:  uploaderID,
+	}
+	if err := repos.Materials.CreateWithPoints(ctx, m, 10); err != nil {
+		t.Fatalf("CreateWithPoints: %v", err)
+	}
+	return m
+}
+
+func points(t *testing.T, repos models.Repositories, userID int) int {
+	t.Helper()
+	u, err := repos.Users.GetByID(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetByID(%d): %v", userID, err)
+	}
+	return u.Points
+}
+
+func wantNoRows(t *testing.T, what string, err error) {
+	t.Helper()
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("%s: got %v, want sql.ErrNoRows", what, err)
+	}
+}
+
+func testUserLifecycle(t *testing.T, repos models.Repositories) {
+	users, _, err := repos.Users.GetAll(ctx, 0, 0)
+	if err != nil || users == nil || len(users) != 0 {
+		t.Fatalf("GetAll on empty storage = %v, %v; want an empty slice", users, err)
+	}
+
+	u := newUser(t, repos, 1)
+	got, err := repos.Users.GetByEmail(ctx, u.Email)
+	if err != nil || got.ID != u.ID {
+		t.Fatalf("GetByEmail = %v, %v; want user %d", got, err, u.ID)
+	}
+
+	u.FirstName = "Maria"
+	if err := repos.Users.Update(ctx, u); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got, _ := repos.Users.GetByID(ctx, u.ID); got.FirstName != "Maria" {
+		t.Errorf("FirstName after Update = %q, want Maria", got.FirstName)
+	}
+
+	if err := repos.Users.Delete(ctx, u.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	_, err = repos.Users.GetByID(ctx, u.ID)
+	wantNoRows(t, "GetByID of deleted user", err)
+	wantNoRows(t, "Update of deleted user", repos.Users.Update(ctx, u))
+	wantNoRows(t, "Delete of deleted user", repos.Users.Delete(ctx, u.ID))
+	if deleted, _, _ := repos.Users.GetDeleted(ctx, 0, 0); len(deleted) != 1 || deleted[0].ID != u.ID {
+		t.Errorf("GetDeleted = %v, want user %d", deleted, u.ID)
+	}
+
+	if err := repos.Users.Restore(ctx, u.ID); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if _, err := repos.Users.GetByID(ctx, u.ID); err != nil {
+		t.Errorf("GetByID after Restore: %v", err)
+	}
+}
+
+func testUserUniqueness(t *testing.T, repos models.Repositories) {
+	u := newUser(t, repos, 1)
+	dup := &models.User{StudentNumber: "202099999", FirstName: "A", LastName: "B", Email: u.Email, IsStudent: true, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := repos.Users.Register(ctx, dup, "hash", "verify-dup", time.Now().Add(time.Hour)); err == nil {
+		t.Error("Register with a taken email succeeded")
+	}
+	if users, _, _ := repos.Users.GetAll(ctx, 0, 0); len(users) != 1 {
+		t.Errorf("GetAll after rejected Register returned %d users, want 1", len(users))
+	}
+}
+
+func testHandlesIgnoreCase(t *testing.T, repos models.Repositories) {
+	a := newUser(t, repos, 1)
+	b := newUser(t, repos, 2)
+	if err := repos.Users.UpdatePrivacySettings(ctx, a.ID, &models.PrivacySettings{Handle: "Juan_DC"}); err != nil {
+		t.Fatalf("UpdatePrivacySettings: %v", err)
+	}
+	err := repos.Users.UpdatePrivacySettings(ctx, b.ID, &models.PrivacySettings{Handle: "juan_dc"})
+	if !errors.Is(err, models.ErrHandleTaken) {
+		t.Errorf("UpdatePrivacySettings with a handle differing in case = %v, want ErrHandleTaken", err)
+	}
+	if taken, _ := repos.Users.IsHandleTaken(ctx, "JUAN_DC", b.ID); !taken {
+		t.Error("IsHandleTaken ignored case")
+	}
+	found, err := repos.Users.GetByHandles(ctx, []string{"JUAN_dc"})
+	if err != nil || len(found) != 1 || found[0].ID != a.ID {
+		t.Errorf("GetByHandles = %v, %v; want user %d", found, err, a.ID)
+	}
+}
+
+func testUserTokens(t *testing.T, repos models.Repositories) {
+	u := newUser(t, repos, 1)
+	id, err := repos.Users.VerifyEmail(ctx, "verify-1")
+	if err != nil || id != u.ID {
+		t.Fatalf("VerifyEmail = %d, %v; want %d", id, err, u.ID)
+	}
+	_, err = repos.Users.VerifyEmail(ctx, "verify-1")
+	wantNoRows(t, "VerifyEmail with a spent token", err)
+
+	pat := &models.APIToken{UserID: u.ID, Name: "ci", Prefix: "isk_pat_abcd", Scopes: []string{models.ScopeMaterialsRead}, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := repos.Tokens.Create(ctx, pat, "pat-hash"); err != nil {
+		t.Fatalf("Create token: %v", err)
+	}
+	before, _ := repos.Users.SessionVersion(ctx, u.ID)
+	after, err := repos.Users.ChangePassword(ctx, u.ID, "new-hash")
+	if err != nil || after <= before {
+		t.Fatalf("ChangePassword = %d, %v; want a session version above %d", after, err, before)
+	}
+	if hash, _ := repos.Users.GetPasswordHash(ctx, u.ID); hash != "new-hash" {
+		t.Errorf("GetPasswordHash = %q, want new-hash", hash)
+	}
+	_, err = repos.Tokens.Authenticate(ctx, "pat-hash")
+	wantNoRows(t, "Authenticate with a token from before ChangePassword", err)
+
+	expires := time.Now().Add(time.Hour)
+	if err := repos.Users.StoreOTP(ctx, u.ID, "otp", expires); err != nil {
+		t.Fatalf("StoreOTP: %v", err)
+	}
+	wantNoRows(t, "ExchangeOTP with a wrong code", repos.Users.ExchangeOTP(ctx, u.ID, "wrong", "reset", expires))
+	if err := repos.Users.ExchangeOTP(ctx, u.ID, "otp", "reset", expires); err != nil {
+		t.Fatalf("ExchangeOTP: %v", err)
+	}
+	if err := repos.Tokens.Create(ctx, &models.APIToken{UserID: u.ID, Name: "cli", Prefix: "isk_pat_efgh", Scopes: []string{models.ScopeMaterialsRead}, ExpiresAt: expires}, "pat-hash-2"); err != nil {
+		t.Fatalf("Create token: %v", err)
+	}
+	before, _ = repos.Users.SessionVersion(ctx, u.ID)
+	if err := repos.Users.ResetPassword(ctx, u.ID, "reset", "reset-hash"); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if v, _ := repos.Users.SessionVersion(ctx, u.ID); v <= before {
+		t.Errorf("session version after ResetPassword = %d, want above %d", v, before)
+	}
+	_, err = repos.Tokens.Authenticate(ctx, "pat-hash-2")
+	wantNoRows(t, "Authenticate with a token from before ResetPassword", err)
+	wantNoRows(t, "ResetPassword with a spent token", repos.Users.ResetPassword(ctx, u.ID, "reset", "other"))
+}
+
+// testOTPFailureWindow checks that wrong OTP guesses count per user, so
+// requesting a fresh OTP does not reset them.
+func testOTPFailureWindow(t *testing.T, repos models.Repositories) {
+	u := newUser(t, repos, 1)
+	expires := time.Now().Add(time.Hour)
+	guessWrong := func(n int) error {
+		var err error
+		for i := 0; i < n; i++ {
+			if err = repos.Users.ExchangeOTP(ctx, u.ID, "wrong", "reset", expires); !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+		}
+		return err
+	}
+
+	if err := repos.Users.StoreOTP(ctx, u.ID, "otp-1", expires); err != nil {
+		t.Fatalf("StoreOTP: %v", err)
+	}
+	if err := guessWrong(models.MaxOTPAttempts); !errors.Is(err, models.ErrTooManyAttempts) {
+		t.Fatalf("guess %d on one OTP = %v, want ErrTooManyAttempts", models.MaxOTPAttempts, err)
+	}
+	wantNoRows(t, "ExchangeOTP with a burned OTP", repos.Users.ExchangeOTP(ctx, u.ID, "otp-1", "reset", expires))
+
+	if err := repos.Users.StoreOTP(ctx, u.ID, "otp-2", expires); err != nil {
+		t.Fatalf("StoreOTP: %v", err)
+	}
+	left := models.MaxOTPFailures - models.MaxOTPAttempts
+	if err := guessWrong(left - 1); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("guesses below the window limit = %v, want sql.ErrNoRows", err)
+	}
+	if err := guessWrong(1); !errors.Is(err, models.ErrTooManyAttempts) {
+		t.Fatalf("guess %d in the window = %v, want ErrTooManyAttempts", models.MaxOTPFailures, err)
+	}
+	if err := repos.Users.StoreOTP(ctx, u.ID, "otp-3", expires); !errors.Is(err, models.ErrTooManyAttempts) {
+		t.Fatalf("StoreOTP out of guesses = %v, want ErrTooManyAttempts", err)
+	}
+	wantNoRows(t, "ExchangeOTP with no OTP issued", repos.Users.ExchangeOTP(ctx, u.ID, "otp-3", "reset", expires))
+
+	// Other users keep their own guesses.
+	other := newUser(t, repos, 2)
+	if err := repos.Users.StoreOTP(ctx, other.ID, "otp", expires); err != nil {
+		t.Fatalf("StoreOTP for another user: %v", err)
+	}
+	if err := repos.Users.ExchangeOTP(ctx, other.ID, "otp", "reset", expires); err != nil {
+		t.Fatalf("ExchangeOTP for another user: %v", err)
+	}
+}
+
+func testModeratorRevokesSessions(t *testing.T, repos models.Repositories) {
+	u := newUser(t, repos, 1)
+	version := func() int {
+		v, err := repos.Users.SessionVersion(ctx, u.ID)
+		if err != nil {
+			t.Fatalf("SessionVersion: %v", err)
+		}
+		return v
+	}
+	start := version()
+	if err := repos.Users.SetModerator(ctx, u.ID, true); err != nil {
+		t.Fatalf("SetModerator: %v", err)
+	}
+	promoted := version()
+	if promoted <= start {
+		t.Errorf("session version after promotion = %d, want above %d", promoted, start)
+	}
+	if err := repos.Users.SetModerator(ctx, u.ID, true); err != nil {
+		t.Fatalf("SetModerator: %v", err)
+	}
+	if v := version(); v != promoted {
+		t.Errorf("SetModerator without a change moved the session version to %d", v)
+	}
+	if err := repos.Users.SetModerator(ctx, u.ID, false); err != nil {
+		t.Fatalf("SetModerator: %v", err)
+	}
+	if v := version(); v <= promoted {
+		t.Errorf("session version after demotion = %d, want above %d", v, promoted)
+	}
+}
+
+func testMaterialPoints(t *testing.T, repos models.Repositories) {
+	u := newUser(t, repos, 1)
+	m := newMaterial(t, repos, u.ID, "midterms")
+	if got := points(t, repos, u.ID); got != 10 {
+		t.Fatalf("points after upload = %d, want 10", got)
+	}
+	if badges, _ := repos.Users.GetBadges(ctx, u.ID); len(badges) == 0 {
+		t.Error("first upload awarded no badge")
+	}
+
+	if err := repos.Materials.Delete(ctx, m.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if got := points(t, repos, u.ID); got != 0 {
+		t.Errorf("points after delete = %d, want 0", got)
+	}
+	_, err := repos.Materials.GetByID(ctx, m.ID)
+	wantNoRows(t, "GetByID of deleted material", err)
+	m.Title = "finals"
+	wantNoRows(t, "Update of deleted material", repos.Materials.Update(ctx, m))
+
+	if err := repos.Materials.Restore(ctx, m.ID); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if got := points(t, repos, u.ID); got != 10 {
+		t.Errorf("points after restore = %d, want 10", got)
+	}
+}
+
+func testMaterialVotes(t *testing.T, repos models.Repositories) {
+	uploader := newUser(t, repos, 1)
+	voter := newUser(t, repos, 2)
+	m := newMaterial(t, repos, uploader.ID, "midterms")
+
+	if err := repos.Materials.Vote(ctx, m.ID, voter.ID, "UPVOTE"); err != nil {
+		t.Fatalf("Vote: %v", err)
+	}
+	if err := repos.Materials.Vote(ctx, m.ID, uploader.ID, "UPVOTE"); err != nil {
+		t.Fatalf("Vote: %v", err)
+	}
+	if err := repos.Materials.Vote(ctx, m.ID, uploader.ID, "DOWNVOTE"); err != nil {
+		t.Fatalf("Vote: %v", err)
+	}
+	got, err := repos.Materials.GetByID(ctx, m.ID)
+	if err != nil || got.VoteCount != 0 {
+		t.Fatalf("VoteCount = %v, %v; want 0 after a changed vote", got, err)
+	}
+
+	if err := repos.Materials.Bookmark(ctx, m.ID, voter.ID); err != nil {
+		t.Fatalf("Bookmark: %v", err)
+	}
+	if err := repos.Materials.Bookmark(ctx, m.ID, voter.ID); err != nil {
+		t.Fatalf("repeated Bookmark: %v", err)
+	}
+	if marks, _, _ := repos.Materials.GetBookmarks(ctx, voter.ID, 0, 0); len(marks) != 1 || marks[0].ID != m.ID {
+		t.Errorf("GetBookmarks = %v, want material %d once", marks, m.ID)
+	}
+
+	if err := repos.Users.Delete(ctx, uploader.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if list, _, _ := repos.Materials.List(ctx, 0, 0); len(list) != 0 {
+		t.Errorf("List shows %d materials of a deleted uploader", len(list))
+	}
+}
+
+func testListPages(t *testing.T, repos models.Repositories) {
+	u := newUser(t, repos, 1)
+	newUser(t, repos, 2)
+	last := newUser(t, repos, 3)
+	var ids []int
+	for _, title := range []string{"first", "second", "third"} {
+		ids = append(ids, newMaterial(t, repos, u.ID, title).ID)
+	}
+
+	list, total, err := repos.Materials.List(ctx, 2, 1)
+	if err != nil || total != 3 || len(list) != 2 || list[0].ID != ids[1] || list[1].ID != ids[0] {
+		t.Errorf("List(2, 1) = %v, %d, %v; want materials %d and %d of 3", list, total, err, ids[1], ids[0])
+	}
+	if list, total, _ := repos.Materials.List(ctx, 0, 0); total != 3 || len(list) != 3 || list[0].ID != ids[2] {
+		t.Errorf("List(0, 0) = %v, %d; want all 3, newest first", list, total)
+	}
+	if list, total, _ := repos.Materials.List(ctx, 2, 5); list == nil || len(list) != 0 || total != 3 {
+		t.Errorf("List past the end = %v, %d; want an empty slice of 3", list, total)
+	}
+	if users, total, _ := repos.Users.GetAll(ctx, 1, 0); total != 3 || len(users) != 1 || users[0].ID != last.ID {
+		t.Errorf("GetAll(1, 0) = %v, %d; want user %d of 3", users, total, last.ID)
+	}
+}
+
+func testComments(t *testing.T, repos models.Repositories) {
+	u := newUser(t, repos, 1)
+	other := newUser(t, repos, 2)
+	m := newMaterial(t, repos, u.ID, "midterms")
+
+	var top []*models.Comment
+	for i := 0; i < 3; i++ {
+		c := &models.Comment{MaterialID: m.ID, AuthorID: u.ID, Body: fmt.Sprintf("comment %d", i)}
+		if err := repos.Comments.Create(ctx, c); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		top = append(top, c)
+	}
+	reply := &models.Comment{MaterialID: m.ID, AuthorID: other.ID, ParentID: &top[0].ID, Body: "reply", MentionIDs: []int{u.ID}}
+	if err := repos.Comments.Create(ctx, reply); err != nil {
+		t.Fatalf("Create reply: %v", err)
+	}
+
+	list, total, err := repos.Comments.ListByMaterial(ctx, m.ID, 2, 1)
+	if err != nil || total != 3 || len(list) != 2 || list[0].ID != top[1].ID {
+		t.Fatalf("ListByMaterial(limit 2, offset 1) = %v, %d, %v; want comments %d and %d of 3", list, total, err, top[1].ID, top[2].ID)
+	}
+	if list, _, _ := repos.Comments.ListByMaterial(ctx, m.ID, 10, 5); list == nil || len(list) != 0 {
+		t.Errorf("ListByMaterial past the end = %v, want an empty slice", list)
+	}
+
+	first, err := repos.Comments.GetByID(ctx, top[0].ID)
+	if err != nil || first.ReplyCount != 1 || first.Author == nil || first.Author.ID != u.ID {
+		t.Fatalf("GetByID = %+v, %v; want one reply by a known author", first, err)
+	}
+	replies, total, _ := repos.Comments.ListReplies(ctx, top[0].ID, 10, 0)
+	if total != 1 || len(replies) != 1 || len(replies[0].MentionIDs) != 1 || replies[0].MentionIDs[0] != u.ID {
+		t.Errorf("ListReplies = %v, %d; want the reply mentioning user %d", replies, total, u.ID)
+	}
+
+	if err := repos.Comments.SoftDelete(ctx, top[0].ID); err != nil {
+		t.Fatalf("SoftDelete: %v", err)
+	}
+	deleted, _ := repos.Comments.GetByID(ctx, top[0].ID)
+	if !deleted.Deleted || deleted.Body != "" || deleted.ReplyCount != 1 {
+		t.Errorf("deleted comment = %+v; want it kept in the thread without its body", deleted)
+	}
+	wantNoRows(t, "Update of a deleted comment", repos.Comments.Update(ctx, top[0].ID, "edit", nil))
+	_, err = repos.Comments.GetByID(ctx, 999)
+	wantNoRows(t, "GetByID of a missing comment", err)
+}
+
+func testCommentEdits(t *testing.T, repos models.Repositories) {
+	a := newUser(t, repos, 1)
+	b := newUser(t, repos, 2)
+	m := newMaterial(t, repos, a.ID, "midterms")
+	c := &models.Comment{MaterialID: m.ID, AuthorID: a.ID, Body: "first", MentionIDs: []int{a.ID}}
+	if err := repos.Comments.Create(ctx, c); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := repos.Comments.Update(ctx, c.ID, "second", []int{b.ID}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := repos.Comments.Update(ctx, c.ID, "third", []int{b.ID, a.ID}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	got, _ := repos.Comments.GetByID(ctx, c.ID)
+	if got.Body != "third" || !got.Edited {
+		t.Errorf("edited comment = %+v; want body third, marked edited", got)
+	}
+	if len(got.MentionIDs) != 2 || got.MentionIDs[0] != a.ID || got.MentionIDs[1] != b.ID {
+		t.Errorf("MentionIDs = %v, want [%d %d]", got.MentionIDs, a.ID, b.ID)
+	}
+	history, err := repos.Comments.GetHistory(ctx, c.ID)
+	if err != nil || len(history) != 2 || history[0].Body != "second" || history[1].Body != "first" {
+		t.Errorf("GetHistory = %v, %v; want second then first", history, err)
+	}
+}
+
+func testReports(t *testing.T, repos models.Repositories) {
+	uploader := newUser(t, repos, 1)
+	reporter := newUser(t, repos, 2)
+	moderator := newUser(t, repos, 3)
+	m := newMaterial(t, repos, uploader.ID, "midterms")
+
+	id, err := repos.Reports.Create(ctx, m.ID, reporter.ID, "spam", "ads")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	_, err = repos.Reports.Create(ctx, m.ID, reporter.ID, "other", "again")
+	wantNoRows(t, "second pending report by the same user", err)
+	if _, err := repos.Reports.Create(ctx, m.ID, uploader.ID, "copyright", ""); err != nil {
+		t.Fatalf("Create by another user: %v", err)
+	}
+
+	if err := repos.Reports.SetStatus(ctx, id, models.ReportReviewing, moderator.ID); err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+	wantNoRows(t, "SetStatus of a missing report", repos.Reports.SetStatus(ctx, 999, models.ReportReviewing, moderator.ID))
+
+	list, total, err := repos.Reports.List(ctx, models.ReportFilter{Status: models.ReportReviewing, Limit: 10})
+	if err != nil || total != 1 || len(list) != 1 || list[0].ID != id {
+		t.Fatalf("List(reviewing) = %v, %d, %v; want report %d", list, total, err, id)
+	}
+	r := list[0]
+	if r.MaterialTitle != "midterms" || r.Reporter == nil || r.Reporter.ID != reporter.ID || r.HandledBy == nil || *r.HandledBy != moderator.ID {
+		t.Errorf("listed report = %+v", r)
+	}
+	if list, total, _ := repos.Reports.List(ctx, models.ReportFilter{Reason: "copyright", Limit: 10}); total != 1 || len(list) != 1 {
+		t.Errorf("List(copyright) = %v, %d; want one report", list, total)
+	}
+	if list, total, _ := repos.Reports.List(ctx, models.ReportFilter{Limit: 1, Offset: 1}); total != 2 || len(list) != 1 {
+		t.Errorf("List(limit 1, offset 1) = %v, %d; want one of two reports", list, total)
+	}
+}
+
+func testModerationActions(t *testing.T, repos models.Repositories) {
+	uploader := newUser(t, repos, 1)
+	a := newUser(t, repos, 2)
+	b := newUser(t, repos, 3)
+	moderator := newUser(t, repos, 4)
+	m := newMaterial(t, repos, uploader.ID, "midterms")
+	first, _ := repos.Reports.Create(ctx, m.ID, a.ID, "spam", "")
+	second, _ := repos.Reports.Create(ctx, m.ID, b.ID, "spam", "")
+
+	if err := repos.Reports.Apply(ctx, first, models.ModerationHide, moderator.ID); err != nil {
+		t.Fatalf("Apply(hide): %v", err)
+	}
+	if list, _, _ := repos.Materials.List(ctx, 0, 0); len(list) != 0 {
+		t.Error("hidden material still listed")
+	}
+	if all, _, _ := repos.Materials.ListAll(ctx, 0, 0); len(all) != 1 || !all[0].Hidden {
+		t.Errorf("ListAll = %v, want the material marked hidden", all)
+	}
+	reports, _, _ := repos.Reports.List(ctx, models.ReportFilter{Status: models.ReportResolved, Limit: 10})
+	if len(reports) != 2 {
+		t.Errorf("hide resolved %d reports, want both pending reports", len(reports))
+	}
+
+	third, _ := repos.Reports.Create(ctx, m.ID, a.ID, "other", "")
+	if err := repos.Reports.Apply(ctx, third, models.ModerationDismiss, moderator.ID); err != nil {
+		t.Fatalf("Apply(dismiss): %v", err)
+	}
+	if err := repos.Reports.Apply(ctx, second, models.ModerationDelete, moderator.ID); err != nil {
+		t.Fatalf("Apply(delete): %v", err)
+	}
+	if got := points(t, repos, uploader.ID); got != 0 {
+		t.Errorf("uploader points after moderation delete = %d, want 0", got)
+	}
+	wantNoRows(t, "Apply(delete) of a deleted material", repos.Reports.Apply(ctx, first, models.ModerationDelete, moderator.ID))
+	wantNoRows(t, "Apply to a missing report", repos.Reports.Apply(ctx, 999, models.ModerationHide, moderator.ID))
+}
+
+func testAudit(t *testing.T, repos models.Repositories) {
+	actor, target := 1, 2
+	for _, action := range []string{"user.delete", "user.restore", "user.delete"} {
+		e := &models.AuditEvent{ActorID: &actor, Action: action, TargetType: "user", TargetID: &target}
+		if err := repos.Audit.Create(ctx, e); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if e.ID == 0 || e.CreatedAt.IsZero() {
+			t.Fatalf("Create left ID or CreatedAt unset: %+v", e)
+		}
+	}
+	if err := repos.Audit.Create(ctx, &models.AuditEvent{Action: "auth.email_verified", TargetType: "user"}); err != nil {
+		t.Fatalf("Create without actor: %v", err)
+	}
+
+	events, total, err := repos.Audit.List(ctx, models.AuditFilter{Action: "user.delete", Limit: 1})
+	if err != nil || total != 2 || len(events) != 1 {
+		t.Fatalf("List(user.delete, limit 1) = %v, %d, %v; want one of two events", events, total, err)
+	}
+	if string(events[0].Diff) != "{}" {
+		t.Errorf("Diff defaulted to %s, want {}", events[0].Diff)
+	}
+	newest := events[0].ID
+
+	var ids []int64
+	err = repos.Audit.Each(ctx, models.AuditFilter{ActorID: &actor}, func(e *models.AuditEvent) error {
+		ids = append(ids, e.ID)
+		return nil
+	})
+	if err != nil || len(ids) != 3 || ids[2] != newest || ids[0] > ids[1] {
+		t.Errorf("Each(actor) visited %v, %v; want three events oldest first", ids, err)
+	}
+
+	future := time.Now().Add(time.Hour)
+	if _, total, _ := repos.Audit.List(ctx, models.AuditFilter{Since: &future, Limit: 10}); total != 0 {
+		t.Errorf("List(since an hour from now) matched %d events", total)
+	}
+}
+
+func testAPITokens(t *testing.T, repos models.Repositories) {
+	u := newUser(t, repos, 1)
+	other := newUser(t, repos, 2)
+	expires := time.Now().Add(time.Hour)
+
+	tok := &models.APIToken{UserID: u.ID, Name: "ci", Prefix: "isk_pat_abcd", Scopes: []string{models.ScopeMaterialsRead}, ExpiresAt: expires}
+	if err := repos.Tokens.Create(ctx, tok, "secret-hash"); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	expired := &models.APIToken{UserID: u.ID, Name: "old", Prefix: "isk_pat_efgh", Scopes: []string{models.ScopeMaterialsRead}, ExpiresAt: time.Now().Add(-time.Hour)}
+	if err := repos.Tokens.Create(ctx, expired, "expired-hash"); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	list, err := repos.Tokens.ListByUser(ctx, u.ID)
+	if err != nil || len(list) != 2 || list[0].ID != expired.ID {
+		t.Fatalf("ListByUser = %v, %v; want both tokens, newest first", list, err)
+	}
+	if n, _ := repos.Tokens.CountByUser(ctx, other.ID); n != 0 {
+		t.Errorf("CountByUser(other) = %d, want 0", n)
+	}
+
+	grant, err := repos.Tokens.Authenticate(ctx, "secret-hash")
+	if err != nil || grant.TokenID != tok.ID || grant.UserID != u.ID || !grant.IsStudent || len(grant.Scopes) != 1 {
+		t.Fatalf("Authenticate = %+v, %v", grant, err)
+	}
+	if list, _ := repos.Tokens.ListByUser(ctx, u.ID); list[1].LastUsedAt == nil {
+		t.Error("Authenticate did not record LastUsedAt")
+	}
+	_, err = repos.Tokens.Authenticate(ctx, "expired-hash")
+	wantNoRows(t, "Authenticate with an expired token", err)
+
+	wantNoRows(t, "Delete of another user's token", repos.Tokens.Delete(ctx, other.ID, tok.ID))
+	if err := repos.Users.Delete(ctx, u.ID); err != nil {
+		t.Fatalf("Delete user: %v", err)
+	}
+	_, err = repos.Tokens.Authenticate(ctx, "secret-hash")
+	wantNoRows(t, "Authenticate for a deleted user", err)
+	if err := repos.Tokens.Delete(ctx, u.ID, tok.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if n, _ := repos.Tokens.CountByUser(ctx, u.ID); n != 1 {
+		t.Errorf("CountByUser after Delete = %d, want 1", n)
+	}
+}
+
+func testExport(t *testing.T, repos models.Repositories) {
+	u := newUser(t, repos, 1)
+	other := newUser(t, repos, 2)
+	mine := newMaterial(t, repos, u.ID, "midterms")
+	theirs := newMaterial(t, repos, other.ID, "finals")
+	if err := repos.Materials.Delete(ctx, mine.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := repos.Materials.Vote(ctx, theirs.ID, u.ID, "UPVOTE"); err != nil {
+		t.Fatalf("Vote: %v", err)
+	}
+	if err := repos.Materials.Bookmark(ctx, theirs.ID, u.ID); err != nil {
+		t.Fatalf("Bookmark: %v", err)
+	}
+
+	a, err := repos.Exports.Activity(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("Activity: %v", err)
+	}
+	if len(a.Uploads) != 1 || a.Uploads[0].ID != mine.ID {
+		t.Errorf("Uploads = %v, want the deleted upload %d", a.Uploads, mine.ID)
+	}
+	if len(a.Votes) != 1 || a.Votes[0].MaterialID != theirs.ID || a.Votes[0].VoteType != "UPVOTE" {
+		t.Errorf("Votes = %v", a.Votes)
+	}
+	if len(a.Bookmarks) != 1 || a.Bookmarks[0].Title != "finals" {
+		t.Errorf("Bookmarks = %v", a.Bookmarks)
+	}
+	if len(a.Points) != 2 || a.Points[0].Points != 10 || a.Points[1].Points != -10 {
+		t.Errorf("Points = %v, want the upload and its reversal", a.Points)
+	}
+
+	empty, err := repos.Exports.Activity(ctx, 999)
+	if err != nil || empty.Uploads == nil || empty.Votes == nil || empty.Bookmarks == nil || empty.Points == nil {
+		t.Errorf("Activity of an unknown user = %+v, %v; want empty slices", empty, err)
+	}
+}
+
+// recordLogin writes an audit event for userID from a known IP address.
+func recordLogin(t *testing.T, repos models.Repositories, userID int) {
+	t.Helper()
+	e := &models.AuditEvent{ActorID: &userID, Action: "auth.login", TargetType: "user", TargetID: &userID, IP: "203.0.113.7"}
+	if err := repos.Audit.Create(ctx, e); err != nil {
+		t.Fatalf("Create audit event: %v", err)
+	}
+}
+
+// wantAuditKept checks that userID's audit events survived a purge without
+// their IP addresses.
+func wantAuditKept(t *testing.T, repos models.Repositories, userID int) {
+	t.Helper()
+	events, total, err := repos.Audit.List(ctx, models.AuditFilter{ActorID: &userID, Limit: 10})
+	if err != nil || total != 1 {
+		t.Fatalf("audit events after Purge = %v, %d, %v; want the one event kept", events, total, err)
+	}
+	if events[0].IP != "" {
+		t.Errorf("audit event kept IP %q after Purge", events[0].IP)
+	}
+}
+
+func testPurgeDeletes(t *testing.T, repos models.Repositories) {
+	gone := newUser(t, repos, 1)
+	kept := newUser(t, repos, 2)
+	goneMaterial := newMaterial(t, repos, gone.ID, "midterms")
+	deletedMaterial := newMaterial(t, repos, kept.ID, "finals")
+	keptMaterial := newMaterial(t, repos, kept.ID, "quiz")
+	if err := repos.Comments.Create(ctx, &models.Comment{MaterialID: keptMaterial.ID, AuthorID: gone.ID, Body: "hi"}); err != nil {
+		t.Fatalf("Create comment: %v", err)
+	}
+	recordLogin(t, repos, gone.ID)
+	recordLogin(t, repos, kept.ID)
+	if err := repos.Users.Delete(ctx, gone.ID); err != nil {
+		t.Fatalf("Delete user: %v", err)
+	}
+	if err := repos.Materials.Delete(ctx, deletedMaterial.ID); err != nil {
+		t.Fatalf("Delete material: %v", err)
+	}
+
+	result, err := repos.Retention.Purge(time.Now().Add(time.Minute), false)
+	if err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if result.Users != 1 || result.Anonymized != 0 || result.Materials != 2 || len(result.FileURLs) != 2 {
+		t.Errorf("Purge = %+v; want one user and two materials", result)
+	}
+	for _, url := range result.FileURLs {
+		if url != goneMaterial.FileURL && url != deletedMaterial.FileURL {
+			t.Errorf("Purge returned unexpected file %s", url)
+		}
+	}
+	if deleted, _, _ := repos.Users.GetDeleted(ctx, 0, 0); len(deleted) != 0 {
+		t.Errorf("GetDeleted after Purge = %v", deleted)
+	}
+	if comments, total, _ := repos.Comments.ListByMaterial(ctx, keptMaterial.ID, 10, 0); total != 0 {
+		t.Errorf("purged user's comments survived: %v", comments)
+	}
+	if _, err := repos.Materials.GetByID(ctx, keptMaterial.ID); err != nil {
+		t.Errorf("GetByID of an unrelated material: %v", err)
+	}
+	wantAuditKept(t, repos, gone.ID)
+	if events, _, _ := repos.Audit.List(ctx, models.AuditFilter{ActorID: &kept.ID, Limit: 10}); len(events) != 1 || events[0].IP == "" {
+		t.Errorf("Purge blanked the IP of a user it kept: %v", events)
+	}
+
+	again, err := repos.Retention.Purge(time.Now().Add(time.Minute), false)
+	if err != nil || again.Users != 0 || again.Materials != 0 {
+		t.Errorf("second Purge = %+v, %v; want nothing left to purge", again, err)
+	}
+}
+
+func testPurgeAnonymizes(t *testing.T, repos models.Repositories) {
+	gone := newUser(t, repos, 1)
+	m := newMaterial(t, repos, gone.ID, "midterms")
+	tok := &models.APIToken{UserID: gone.ID, Name: "ci", Prefix: "isk_pat_abcd", Scopes: []string{models.ScopeMaterialsRead}, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := repos.Tokens.Create(ctx, tok, "secret-hash"); err != nil {
+		t.Fatalf("Create token: %v", err)
+	}
+	recordLogin(t, repos, gone.ID)
+	if err := repos.Users.Delete(ctx, gone.ID); err != nil {
+		t.Fatalf("Delete user: %v", err)
+	}
+
+	result, err := repos.Retention.Purge(time.Now().Add(time.Minute), true)
+	if err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if result.Users != 0 || result.Anonymized != 1 || result.Materials != 0 {
+		t.Errorf("Purge = %+v; want one anonymized user and no materials", result)
+	}
+
+	got, err := repos.Materials.GetByID(ctx, m.ID)
+	if err != nil {
+		t.Fatalf("GetByID of an anonymized user's material: %v", err)
+	}
+	if got.Uploader == nil || got.Uploader.DisplayName != "Deleted User" {
+		t.Errorf("Uploader = %+v, want Deleted User", got.Uploader)
+	}
+	if deleted, _, _ := repos.Users.GetDeleted(ctx, 0, 0); len(deleted) != 0 {
+		t.Errorf("GetDeleted still lists anonymized users: %v", deleted)
+	}
+	if n, _ := repos.Tokens.CountByUser(ctx, gone.ID); n != 0 {
+		t.Errorf("anonymized user kept %d API tokens", n)
+	}
+	wantAuditKept(t, repos, gone.ID)
+	if again, _ := repos.Retention.Purge(time.Now().Add(time.Minute), true); again.Anonymized != 0 {
+		t.Errorf("second Purge anonymized %d users again", again.Anonymized)
+	}
+}
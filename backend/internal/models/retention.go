@@ -0,0 +1,132 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type PurgeResult struct {
+	Users      int
+	Anonymized int
+	Materials  int
+	Tokens     int
+	// FileURLs are the files of purged materials. They are returned rather
+	// than removed here so storage cleanup only happens after the rows are
+	// gone.
+	FileURLs []string
+}
+
+type RetentionModel struct {
+	db *sql.DB
+}
+
+func NewRetentionModel(db *sql.DB) *RetentionModel {
+	return &RetentionModel{db: db}
+}
+
+// Purge permanently removes users and materials soft-deleted before cutoff,
+// and user tokens spent or expired before it, in a single transaction. With
+// anonymize unset a purged user takes their materials and credentials with
+// them; otherwise see anonymizeUsers. Either way their audit events stay,
+// keyed by ID alone: diffs never hold personal values, and the IP addresses
+// are blanked here.
+func (m *RetentionModel) Purge(cutoff time.Time, anonymize bool) (*PurgeResult, error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userIDs pq.Int64Array
+	if err := tx.QueryRow("SELECT ARRAY(SELECT id FROM users WHERE deleted_at < $1 AND anonymized_at IS NULL)", cutoff).Scan(&userIDs); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`
+		DELETE FROM materials
+		WHERE deleted_at < $1 OR (uploader_id = ANY($2) AND NOT $3)
+		RETURNING file_url`, cutoff, userIDs, anonymize)
+	if err != nil {
+		return nil, err
+	}
+	result := &PurgeResult{}
+	for rows.Next() {
+		var fileURL string
+		if err := rows.Scan(&fileURL); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		result.FileURLs = append(result.FileURLs, fileURL)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	result.Materials = len(result.FileURLs)
+
+	if len(userIDs) > 0 {
+		if _, err := tx.Exec("UPDATE audit_events SET ip = '' WHERE actor_id = ANY($1) AND ip <> ''", userIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(userIDs) > 0 && anonymize {
+		if err := anonymizeUsers(tx, userIDs); err != nil {
+			return nil, err
+		}
+		result.Anonymized = len(userIDs)
+	} else if len(userIDs) > 0 {
+		if _, err := tx.Exec("DELETE FROM users WHERE id = ANY($1)", userIDs); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec("DELETE FROM user_credentials WHERE id = ANY($1)", userIDs); err != nil {
+			return nil, err
+		}
+		result.Users = len(userIDs)
+	}
+
+	// Tokens outlive OTPFailureWindow so wrong OTP guesses keep counting.
+	tokens, err := tx.Exec(`
+		DELETE FROM user_tokens
+		WHERE (expires_at < $1 OR consumed_at < $1) AND created_at < NOW() - make_interval(secs => $2)`,
+		cutoff, OTPFailureWindow.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	n, _ := tokens.RowsAffected()
+	result.Tokens = int(n)
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// anonymizeUsers scrubs every personal field from the users but keeps the
+// rows, so their materials, comments and votes survive under a "Deleted
+// User" placeholder. Private records such as bookmarks and tokens are
+// removed, and the emptied password hash means the account can never log in
+// again. The rows stay soft-deleted; anonymized_at keeps them from being
+// purged twice.
+func anonymizeUsers(tx *sql.Tx, userIDs pq.Int64Array) error {
+	for _, query := range []string{
+		"DELETE FROM bookmarks WHERE user_id = ANY($1)",
+		"DELETE FROM api_tokens WHERE user_id = ANY($1)",
+		"DELETE FROM user_tokens WHERE user_id = ANY($1)",
+		`UPDATE users SET
+			student_number = NULL, first_name = 'Deleted', last_name = 'User',
+			email = 'deleted-' || id || '@invalid', email_verified = false,
+			college = NULL, course = NULL, handle = NULL,
+			hide_real_name = false, hide_activity = true, is_moderator = false,
+			anonymized_at = NOW(), updated_at = NOW()
+		WHERE id = ANY($1)`,
+		"UPDATE user_credentials SET password_hash = '' WHERE id = ANY($1)",
+	} {
+		if _, err := tx.Exec(query, userIDs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
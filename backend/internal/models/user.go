@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"time"
 )
@@ -11,138 +12,225 @@ type User struct {
 	FirstName     string    `json:"first_name"`
 	LastName      string    `json:"last_name"`
 	Email         string    `json:"email"`
+	College       string    `json:"college,omitempty"`
+	Course        string    `json:"course,omitempty"`
 	IsStudent     bool      `json:"is_student"`
+	IsModerator   bool      `json:"is_moderator"`
 	Points        int       `json:"points"`
 	EmailVerified bool      `json:"email_verified"`
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
+	// SessionVersion is embedded in the user's JWTs; see UserModel.SessionVersion.
+	SessionVersion int `json:"-"`
 }
 
 type UserModel struct {
-	db *sql.DB
+	db   *sql.DB
+	opts QueryOptions
 }
 
-func NewUserModel(db *sql.DB) *UserModel {
-	return &UserModel{db: db}
+func NewUserModel(db *sql.DB, opts QueryOptions) *UserModel {
+	return &UserModel{db: db, opts: opts}
 }
 
-func (m *UserModel) Create(tx *sql.Tx, user *User) error {
+// Register creates the credentials row, the user row and the email
+// verification token in one transaction. user.ID is set from the
+// credentials row.
+func (m *UserModel) Register(ctx context.Context, user *User, passwordHash, verificationHash string, tokenExpiresAt time.Time) error {
+	ctx, done := m.opts.begin(ctx, "UserModel.Register")
+	defer done()
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO user_credentials (email, password_hash, created_at)
+		VALUES ($1, $2, $3) RETURNING id`,
+		user.Email, passwordHash, user.CreatedAt,
+	).Scan(&user.ID)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		INSERT INTO users (id, student_number, first_name, last_name, email, is_student, points, email_verified, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
-	_, err := tx.Exec(query, user.ID, user.StudentNumber, user.FirstName, user.LastName, user.Email, user.IsStudent, user.Points, user.EmailVerified, user.CreatedAt, user.UpdatedAt)
-	return err
+	_, err = tx.ExecContext(ctx, query, user.ID, user.StudentNumber, user.FirstName, user.LastName, user.Email, user.IsStudent, user.Points, user.EmailVerified, user.CreatedAt, user.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := insertToken(ctx, tx, user.ID, TokenEmailVerify, verificationHash, tokenExpiresAt); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (m *UserModel) GetByID(id int) (*User, error) {
+func (m *UserModel) GetByID(ctx context.Context, id int) (*User, error) {
+	ctx, done := m.opts.begin(ctx, "UserModel.GetByID")
+	defer done()
 	query := `
-		SELECT id, student_number, first_name, last_name, email, is_student, points, email_verified, created_at, updated_at
-		FROM users WHERE id = $1
+		SELECT id, student_number, first_name, last_name, email, COALESCE(college, ''), COALESCE(course, ''), is_student, is_moderator, points, email_verified, created_at, updated_at, session_version
+		FROM users WHERE id = $1 AND deleted_at IS NULL
 	`
 	var user User
-	err := m.db.QueryRow(query, id).Scan(&user.ID, &user.StudentNumber, &user.FirstName, &user.LastName, &user.Email, &user.IsStudent, &user.Points, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
+	err := m.db.QueryRowContext(ctx, query, id).Scan(&user.ID, &user.StudentNumber, &user.FirstName, &user.LastName, &user.Email, &user.College, &user.Course, &user.IsStudent, &user.IsModerator, &user.Points, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt, &user.SessionVersion)
 	if err == sql.ErrNoRows {
 		return nil, sql.ErrNoRows
 	}
 	return &user, err
 }
 
-func (m *UserModel) GetByEmail(email string) (*User, error) {
+func (m *UserModel) GetByEmail(ctx context.Context, email string) (*User, error) {
+	ctx, done := m.opts.begin(ctx, "UserModel.GetByEmail")
+	defer done()
 	query := `
-		SELECT id, student_number, first_name, last_name, email, is_student, points, email_verified, created_at, updated_at
-		FROM users WHERE email = $1
+		SELECT id, student_number, first_name, last_name, email, COALESCE(college, ''), COALESCE(course, ''), is_student, is_moderator, points, email_verified, created_at, updated_at, session_version
+		FROM users WHERE email = $1 AND deleted_at IS NULL
 	`
 	var user User
-	err := m.db.QueryRow(query, email).Scan(&user.ID, &user.StudentNumber, &user.FirstName, &user.LastName, &user.Email, &user.IsStudent, &user.Points, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
+	err := m.db.QueryRowContext(ctx, query, email).Scan(&user.ID, &user.StudentNumber, &user.FirstName, &user.LastName, &user.Email, &user.College, &user.Course, &user.IsStudent, &user.IsModerator, &user.Points, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt, &user.SessionVersion)
 	if err == sql.ErrNoRows {
 		return nil, sql.ErrNoRows
 	}
 	return &user, err
 }
 
-func (m *UserModel) GetByStudentNumber(studentNumber string) (*User, error) {
+func (m *UserModel) GetByStudentNumber(ctx context.Context, studentNumber string) (*User, error) {
+	ctx, done := m.opts.begin(ctx, "UserModel.GetByStudentNumber")
+	defer done()
 	query := `
-		SELECT id, student_number, first_name, last_name, email, is_student, points, email_verified, created_at, updated_at
-		FROM users WHERE student_number = $1
+		SELECT id, student_number, first_name, last_name, email, COALESCE(college, ''), COALESCE(course, ''), is_student, is_moderator, points, email_verified, created_at, updated_at, session_version
+		FROM users WHERE student_number = $1 AND deleted_at IS NULL
 	`
 	var user User
-	err := m.db.QueryRow(query, studentNumber).Scan(&user.ID, &user.StudentNumber, &user.FirstName, &user.LastName, &user.Email, &user.IsStudent, &user.Points, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
+	err := m.db.QueryRowContext(ctx, query, studentNumber).Scan(&user.ID, &user.StudentNumber, &user.FirstName, &user.LastName, &user.Email, &user.College, &user.Course, &user.IsStudent, &user.IsModerator, &user.Points, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt, &user.SessionVersion)
 	if err == sql.ErrNoRows {
 		return nil, sql.ErrNoRows
 	}
 	return &user, err
 }
 
-func (m *UserModel) Update(user *User) error {
+// Update replaces a user's profile fields. It returns sql.ErrNoRows if the
+// user is missing or soft-deleted.
+func (m *UserModel) Update(ctx context.Context, user *User) error {
+	ctx, done := m.opts.begin(ctx, "UserModel.Update")
+	defer done()
 	query := `
-		UPDATE users SET first_name = $1, last_name = $2, updated_at = $3
-		WHERE id = $4
+		UPDATE users SET first_name = $1, last_name = $2, college = NULLIF($3, ''), course = NULLIF($4, ''), updated_at = $5
+		WHERE id = $6 AND deleted_at IS NULL
 	`
-	_, err := m.db.Exec(query, user.FirstName, user.LastName, time.Now(), user.ID)
-	return err
+	result, err := m.db.ExecContext(ctx, query, user.FirstName, user.LastName, user.College, user.Course, time.Now(), user.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
 }
 
-func (m *UserModel) Delete(id int) error {
-	_, err := m.db.Exec("DELETE FROM users WHERE id = $1", id)
-	return err
+// Delete soft-deletes a user. The row, their credentials and their
+// materials are kept until the retention job purges them.
+func (m *UserModel) Delete(ctx context.Context, id int) error {
+	ctx, done := m.opts.begin(ctx, "UserModel.Delete")
+	defer done()
+	result, err := m.db.ExecContext(ctx, "UPDATE users SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL", time.Now(), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
 }
 
-func (m *UserModel) GetAll() ([]*User, error) {
-	query := `
-		SELECT id, student_number, first_name, last_name, email, is_student, points, email_verified, created_at, updated_at
-		FROM users ORDER BY created_at DESC
-	`
-	rows, err := m.db.Query(query)
+func (m *UserModel) Restore(ctx context.Context, id int) error {
+	ctx, done := m.opts.begin(ctx, "UserModel.Restore")
+	defer done()
+	result, err := m.db.ExecContext(ctx, "UPDATE users SET deleted_at = NULL, updated_at = $1 WHERE id = $2 AND deleted_at IS NOT NULL AND anonymized_at IS NULL", time.Now(), id)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
-
-	var users []*User
-	for rows.Next() {
-		var u User
-		if err := rows.Scan(&u.ID, &u.StudentNumber, &u.FirstName, &u.LastName, &u.Email, &u.IsStudent, &u.Points, &u.EmailVerified, &u.CreatedAt, &u.UpdatedAt); err != nil {
-			return nil, err
-		}
-		users = append(users, &u)
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
 	}
-	return users, rows.Err()
+	return nil
+}
+
+// GetDeleted returns one page of soft-deleted users awaiting purge, most
+// recent first, along with their total.
+func (m *UserModel) GetDeleted(ctx context.Context, limit, offset int) ([]*User, int, error) {
+	ctx, done := m.opts.begin(ctx, "UserModel.GetDeleted")
+	defer done()
+	return m.list(ctx, "WHERE deleted_at IS NOT NULL AND anonymized_at IS NULL", "deleted_at DESC, id DESC", limit, offset)
 }
 
-func (m *UserModel) GetLeaderboard(limit int) ([]*User, error) {
+// GetAll returns one page of live users, newest first, along with their
+// total. A limit of zero returns every user.
+func (m *UserModel) GetAll(ctx context.Context, limit, offset int) ([]*User, int, error) {
+	ctx, done := m.opts.begin(ctx, "UserModel.GetAll")
+	defer done()
+	return m.list(ctx, "WHERE deleted_at IS NULL", "created_at DESC, id DESC", limit, offset)
+}
+
+func (m *UserModel) list(ctx context.Context, where, order string, limit, offset int) ([]*User, int, error) {
+	var total int
+	if err := m.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users "+where).Scan(&total); err != nil {
+		return nil, 0, err
+	}
 	query := `
-		SELECT id, student_number, first_name, last_name, email, is_student, points, email_verified, created_at, updated_at
-		FROM users WHERE is_student = true
-		ORDER BY points DESC LIMIT $1
+		SELECT id, student_number, first_name, last_name, email, COALESCE(college, ''), COALESCE(course, ''), is_student, is_moderator, points, email_verified, created_at, updated_at
+		FROM users ` + where + `
+		ORDER BY ` + order + `
+		LIMIT $1 OFFSET $2
 	`
-	rows, err := m.db.Query(query, limit)
+	rows, err := m.db.QueryContext(ctx, query, limitArg(limit), offset)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var users []*User
+	users := []*User{}
 	for rows.Next() {
 		var u User
-		if err := rows.Scan(&u.ID, &u.StudentNumber, &u.FirstName, &u.LastName, &u.Email, &u.IsStudent, &u.Points, &u.EmailVerified, &u.CreatedAt, &u.UpdatedAt); err != nil {
-			return nil, err
+		if err := rows.Scan(&u.ID, &u.StudentNumber, &u.FirstName, &u.LastName, &u.Email, &u.College, &u.Course, &u.IsStudent, &u.IsModerator, &u.Points, &u.EmailVerified, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, 0, err
 		}
 		users = append(users, &u)
 	}
-	return users, rows.Err()
+	return users, total, rows.Err()
 }
 
-func (m *UserModel) IncrementPointsAndCheckBadges(userID, points int) error {
-	tx, err := m.db.Begin()
+func (m *UserModel) IncrementPointsAndCheckBadges(ctx context.Context, userID, points int, reason string, materialID *int) error {
+	ctx, done := m.opts.begin(ctx, "UserModel.IncrementPointsAndCheckBadges")
+	defer done()
+	tx, err := m.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	if err := awardPoints(ctx, tx, userID, points, reason, materialID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// awardPoints adds points to the user's total, records the point event and
+// grants any badges the new total unlocks.
+func awardPoints(ctx context.Context, tx *sql.Tx, userID, points int, reason string, materialID *int) error {
 	query := "UPDATE users SET points = points + $1, updated_at = $2 WHERE id = $3 RETURNING points"
 	var newPoints int
-	if err := tx.QueryRow(query, points, time.Now(), userID).Scan(&newPoints); err != nil {
+	if err := tx.QueryRowContext(ctx, query, points, time.Now(), userID).Scan(&newPoints); err != nil {
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, "INSERT INTO point_events (user_id, points, reason, material_id, created_at) VALUES ($1, $2, $3, $4, $5)", userID, points, reason, materialID, time.Now())
+	if err != nil {
 		return err
 	}
 
@@ -151,7 +239,7 @@ func (m *UserModel) IncrementPointsAndCheckBadges(userID, points int) error {
 		WHERE points_required <= $1
 		AND id NOT IN (SELECT badge_id FROM user_badges WHERE user_id = $2)
 	`
-	rows, err := tx.Query(badgesQuery, newPoints, userID)
+	rows, err := tx.QueryContext(ctx, badgesQuery, newPoints, userID)
 	if err != nil {
 		return err
 	}
@@ -162,86 +250,86 @@ func (m *UserModel) IncrementPointsAndCheckBadges(userID, points int) error {
 		if err := rows.Scan(&badgeID); err != nil {
 			return err
 		}
-		_, err := tx.Exec("INSERT INTO user_badges (user_id, badge_id, awarded_at) VALUES ($1, $2, $3)", userID, badgeID, time.Now())
+		_, err := tx.ExecContext(ctx, "INSERT INTO user_badges (user_id, badge_id, awarded_at) VALUES ($1, $2, $3)", userID, badgeID, time.Now())
 		if err != nil {
 			return err
 		}
 	}
-
-	return tx.Commit()
+	return rows.Err()
 }
 
-func (m *UserModel) VerifyEmail(userID int) error {
-	_, err := m.db.Exec("UPDATE users SET email_verified = true, updated_at = $1 WHERE id = $2", time.Now(), userID)
-	return err
-}
-
-func (m *UserModel) GetPasswordHash(userID int) (string, error) {
+func (m *UserModel) GetPasswordHash(ctx context.Context, userID int) (string, error) {
+	ctx, done := m.opts.begin(ctx, "UserModel.GetPasswordHash")
+	defer done()
 	var hash string
-	err := m.db.QueryRow("SELECT password_hash FROM user_credentials WHERE id = $1", userID).Scan(&hash)
+	err := m.db.QueryRowContext(ctx, "SELECT password_hash FROM user_credentials WHERE id = $1", userID).Scan(&hash)
 	if err == sql.ErrNoRows {
 		return "", sql.ErrNoRows
 	}
 	return hash, err
 }
 
-func (m *UserModel) UpdatePassword(userID int, hash string) error {
-	_, err := m.db.Exec("UPDATE user_credentials SET password_hash = $1 WHERE id = $2", hash, userID)
-	return err
-}
-
-func (m *UserModel) StoreVerificationToken(tx *sql.Tx, userID int, token string, expiresAt time.Time) error {
-	_, err := tx.Exec("INSERT INTO email_verifications (user_id, token, expires_at, created_at) VALUES ($1, $2, $3, $4)", userID, token, expiresAt, time.Now())
-	return err
+// SessionVersion returns the version a user's JWTs must carry to be
+// accepted. It returns sql.ErrNoRows for deleted users.
+func (m *UserModel) SessionVersion(ctx context.Context, userID int) (int, error) {
+	ctx, done := m.opts.begin(ctx, "UserModel.SessionVersion")
+	defer done()
+	var version int
+	err := m.db.QueryRowContext(ctx, "SELECT session_version FROM users WHERE id = $1 AND deleted_at IS NULL", userID).Scan(&version)
+	return version, err
 }
 
-func (m *UserModel) VerifyEmailToken(token string) (int, error) {
-	var userID int
-	err := m.db.QueryRow("SELECT user_id FROM email_verifications WHERE token = $1 AND expires_at > NOW()", token).Scan(&userID)
-	if err == sql.ErrNoRows {
-		return 0, sql.ErrNoRows
+// ChangePassword stores the new hash and bumps the session version in one
+// transaction, revoking every session token issued before along with the
+// user's personal access tokens. It returns the new version.
+func (m *UserModel) ChangePassword(ctx context.Context, userID int, hash string) (int, error) {
+	ctx, done := m.opts.begin(ctx, "UserModel.ChangePassword")
+	defer done()
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
 	}
-	return userID, err
-}
+	defer tx.Rollback()
 
-func (m *UserModel) DeleteVerificationToken(token string) error {
-	_, err := m.db.Exec("DELETE FROM email_verifications WHERE token = $1", token)
-	return err
+	version, err := setPassword(ctx, tx, userID, hash)
+	if err != nil {
+		return 0, err
+	}
+	return version, tx.Commit()
 }
 
-func (m *UserModel) StoreOTP(userID int, otp string, expiresAt time.Time) error {
-	_, err := m.db.Exec("INSERT INTO reset_tokens (user_id, token, expires_at, created_at) VALUES ($1, $2, $3, $4)", userID, otp, expiresAt, time.Now())
-	return err
+// setPassword stores hash, bumps the session version and deletes the user's
+// personal access tokens within tx, so a leaked token can't outlive the
+// password that minted it.
+func setPassword(ctx context.Context, tx *sql.Tx, userID int, hash string) (int, error) {
+	if _, err := tx.ExecContext(ctx, "UPDATE user_credentials SET password_hash = $1 WHERE id = $2", hash, userID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM api_tokens WHERE user_id = $1", userID); err != nil {
+		return 0, err
+	}
+	var version int
+	err := tx.QueryRowContext(ctx, "UPDATE users SET session_version = session_version + 1, updated_at = NOW() WHERE id = $1 RETURNING session_version", userID).Scan(&version)
+	return version, err
 }
 
-func (m *UserModel) VerifyOTP(userID int, otp string) error {
-	var id int
-	err := m.db.QueryRow("SELECT id FROM reset_tokens WHERE user_id = $1 AND token = $2 AND expires_at > NOW()", userID, otp).Scan(&id)
-	if err == sql.ErrNoRows {
-		return sql.ErrNoRows
-	}
+// SetModerator grants or revokes moderator rights on a student. A change
+// bumps the session version, since sessions carry the role in their claims:
+// a demoted moderator must not keep access until their token expires.
+func (m *UserModel) SetModerator(ctx context.Context, userID int, isModerator bool) error {
+	ctx, done := m.opts.begin(ctx, "UserModel.SetModerator")
+	defer done()
+	query := `
+		UPDATE users SET
+			session_version = session_version + CASE WHEN is_moderator <> $1 THEN 1 ELSE 0 END,
+			is_moderator = $1, updated_at = $2
+		WHERE id = $3 AND is_student = true`
+	result, err := m.db.ExecContext(ctx, query, isModerator, time.Now(), userID)
 	if err != nil {
 		return err
 	}
-	_, err = m.db.Exec("DELETE FROM reset_tokens WHERE id = $1", id)
-	return err
-}
-
-func (m *UserModel) StoreResetToken(userID int, token string, expiresAt time.Time) error {
-	_, err := m.db.Exec("INSERT INTO reset_tokens (user_id, token, expires_at, created_at) VALUES ($1, $2, $3, $4)", userID, token, expiresAt, time.Now())
-	return err
-}
-
-func (m *UserModel) VerifyResetToken(userID int, token string) error {
-	var id int
-	err := m.db.QueryRow("SELECT id FROM reset_tokens WHERE user_id = $1 AND token = $2 AND expires_at > NOW()", userID, token).Scan(&id)
-	if err == sql.ErrNoRows {
+	if n, _ := result.RowsAffected(); n == 0 {
 		return sql.ErrNoRows
 	}
-	return err
-}
-
-func (m *UserModel) DeleteResetToken(userID int, token string) error {
-	_, err := m.db.Exec("DELETE FROM reset_tokens WHERE user_id = $1 AND token = $2", userID, token)
-	return err
+	return nil
 }
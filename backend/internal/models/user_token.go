@@ -0,0 +1,285 @@
+package models
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Token purposes. A row in user_tokens is good for exactly one of them, so a
+// value issued for one flow is never accepted by another.
+const (
+	TokenEmailVerify   = "email_verify"
+	TokenEmailChange   = "email_change"
+	TokenPasswordOTP   = "password_otp"
+	TokenPasswordReset = "password_reset"
+)
+
+// MaxOTPAttempts is how many wrong guesses burn a password reset OTP.
+const MaxOTPAttempts = 5
+
+// A user gets MaxOTPFailures wrong guesses across every OTP issued to them in
+// the last OTPFailureWindow, so requesting fresh OTPs buys no extra guesses.
+// This only bounds guessing through the API: a six-digit code hashed with
+// plain SHA-256 is trivial to brute-force offline, so a leaked hash is only
+// harmless because the OTP expires within minutes.
+const (
+	MaxOTPFailures   = 10
+	OTPFailureWindow = time.Hour
+)
+
+var (
+	// ErrEmailTaken is returned by ConfirmEmailChange when the new address
+	// was registered by someone else after the change was requested.
+	ErrEmailTaken = errors.New("email already registered")
+	// ErrTooManyAttempts is returned by ExchangeOTP once the OTP has been
+	// guessed wrong MaxOTPAttempts times, and by StoreOTP and ExchangeOTP
+	// while the user has MaxOTPFailures wrong guesses within
+	// OTPFailureWindow.
+	ErrTooManyAttempts = errors.New("too many attempts")
+)
+
+// Every method below takes the SHA-256 of a token, never the token itself.
+// Tokens are consumed by setting consumed_at in the same transaction as the
+// change they authorize, so each is good for exactly one use.
+
+// VerifyEmail consumes a registration verification token and marks its user
+// verified. It returns the user's ID, or sql.ErrNoRows for unknown, used or
+// expired tokens.
+func (m *UserModel) VerifyEmail(ctx context.Context, tokenHash string) (int, error) {
+	ctx, done := m.opts.begin(ctx, "UserModel.VerifyEmail")
+	defer done()
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	err = tx.QueryRowContext(ctx, `
+		UPDATE user_tokens SET consumed_at = NOW()
+		WHERE purpose = $1 AND token_hash = $2 AND consumed_at IS NULL AND expires_at > NOW()
+		RETURNING user_id`, TokenEmailVerify, tokenHash).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.ExecContext(ctx, "UPDATE users SET email_verified = true, updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL", userID)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return 0, sql.ErrNoRows
+	}
+	return userID, tx.Commit()
+}
+
+// RequestEmailChange stores a token confirming newEmail for the user,
+// replacing any change still pending.
+func (m *UserModel) RequestEmailChange(ctx context.Context, userID int, newEmail, tokenHash string, expiresAt time.Time) error {
+	ctx, done := m.opts.begin(ctx, "UserModel.RequestEmailChange")
+	defer done()
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := revokeTokens(ctx, tx, userID, TokenEmailChange); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, new_email, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`, userID, TokenEmailChange, tokenHash, newEmail, expiresAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ConfirmEmailChange consumes an email change token and applies the change.
+// The address counts as verified since the token was mailed there. It
+// returns sql.ErrNoRows for unknown, used or expired tokens.
+func (m *UserModel) ConfirmEmailChange(ctx context.Context, tokenHash string) (userID int, oldEmail, newEmail string, err error) {
+	ctx, done := m.opts.begin(ctx, "UserModel.ConfirmEmailChange")
+	defer done()
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", "", err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE user_tokens SET consumed_at = NOW()
+		WHERE purpose = $1 AND token_hash = $2 AND consumed_at IS NULL AND expires_at > NOW()
+		RETURNING user_id, new_email`, TokenEmailChange, tokenHash).Scan(&userID, &newEmail)
+	if err != nil {
+		return 0, "", "", err
+	}
+	err = tx.QueryRowContext(ctx, "SELECT email FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", userID).Scan(&oldEmail)
+	if err != nil {
+		return 0, "", "", err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE users SET email = $1, email_verified = true, updated_at = NOW() WHERE id = $2", newEmail, userID)
+	if isUniqueViolation(err) {
+		return 0, "", "", ErrEmailTaken
+	}
+	if err != nil {
+		return 0, "", "", err
+	}
+	return userID, oldEmail, newEmail, tx.Commit()
+}
+
+// StoreOTP starts a password reset. Any OTP or reset token issued earlier
+// stops working. It returns ErrTooManyAttempts instead while the user is out
+// of OTP guesses.
+func (m *UserModel) StoreOTP(ctx context.Context, userID int, otpHash string, expiresAt time.Time) error {
+	ctx, done := m.opts.begin(ctx, "UserModel.StoreOTP")
+	defer done()
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	failures, err := otpFailures(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	if failures >= MaxOTPFailures {
+		return ErrTooManyAttempts
+	}
+	if err := revokeTokens(ctx, tx, userID, TokenPasswordOTP, TokenPasswordReset); err != nil {
+		return err
+	}
+	if err := insertToken(ctx, tx, userID, TokenPasswordOTP, otpHash, expiresAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ExchangeOTP consumes the user's OTP and stores the reset token that
+// replaces it. A wrong guess counts against the OTP and returns
+// sql.ErrNoRows, as does a missing or expired one. The last wrong guess the
+// OTP or the user's failure window allows burns the OTP and returns
+// ErrTooManyAttempts.
+func (m *UserModel) ExchangeOTP(ctx context.Context, userID int, otpHash, resetHash string, expiresAt time.Time) error {
+	ctx, done := m.opts.begin(ctx, "UserModel.ExchangeOTP")
+	defer done()
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var (
+		id       int
+		stored   string
+		attempts int
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, token_hash, attempts FROM user_tokens
+		WHERE user_id = $1 AND purpose = $2 AND consumed_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC LIMIT 1
+		FOR UPDATE`, userID, TokenPasswordOTP).Scan(&id, &stored, &attempts)
+	if err != nil {
+		return err
+	}
+	failures, err := otpFailures(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	if failures >= MaxOTPFailures {
+		if _, err := tx.ExecContext(ctx, "UPDATE user_tokens SET consumed_at = NOW() WHERE id = $1", id); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		return ErrTooManyAttempts
+	}
+
+	if subtle.ConstantTimeCompare([]byte(stored), []byte(otpHash)) != 1 {
+		attempts++
+		burned := attempts >= MaxOTPAttempts || failures+1 >= MaxOTPFailures
+		_, err := tx.ExecContext(ctx, `
+			UPDATE user_tokens
+			SET attempts = $1, consumed_at = CASE WHEN $2 THEN NOW() END
+			WHERE id = $3`, attempts, burned, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		if burned {
+			return ErrTooManyAttempts
+		}
+		return sql.ErrNoRows
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE user_tokens SET consumed_at = NOW() WHERE id = $1", id); err != nil {
+		return err
+	}
+	if err := revokeTokens(ctx, tx, userID, TokenPasswordReset); err != nil {
+		return err
+	}
+	if err := insertToken(ctx, tx, userID, TokenPasswordReset, resetHash, expiresAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ResetPassword consumes a reset token and, through setPassword as
+// ChangePassword does, stores the new hash, bumps the session version and
+// deletes personal access tokens, all in one transaction. It returns
+// sql.ErrNoRows for unknown, used or expired tokens.
+func (m *UserModel) ResetPassword(ctx context.Context, userID int, resetHash, passwordHash string) error {
+	ctx, done := m.opts.begin(ctx, "UserModel.ResetPassword")
+	defer done()
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE user_tokens SET consumed_at = NOW()
+		WHERE user_id = $1 AND purpose = $2 AND token_hash = $3 AND consumed_at IS NULL AND expires_at > NOW()`,
+		userID, TokenPasswordReset, resetHash)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	if _, err := setPassword(ctx, tx, userID, passwordHash); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// otpFailures counts the user's wrong guesses on OTPs issued within
+// OTPFailureWindow.
+func otpFailures(ctx context.Context, tx *sql.Tx, userID int) (int, error) {
+	var n int
+	err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(attempts), 0) FROM user_tokens
+		WHERE user_id = $1 AND purpose = $2 AND created_at > NOW() - make_interval(secs => $3)`,
+		userID, TokenPasswordOTP, OTPFailureWindow.Seconds()).Scan(&n)
+	return n, err
+}
+
+func insertToken(ctx context.Context, tx *sql.Tx, userID int, purpose, tokenHash string, expiresAt time.Time) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4)", userID, purpose, tokenHash, expiresAt)
+	return err
+}
+
+// revokeTokens marks the user's outstanding tokens for purposes as consumed.
+func revokeTokens(ctx context.Context, tx *sql.Tx, userID int, purposes ...string) error {
+	_, err := tx.ExecContext(ctx, "UPDATE user_tokens SET consumed_at = NOW() WHERE user_id = $1 AND purpose = ANY($2) AND consumed_at IS NULL", userID, pq.Array(purposes))
+	return err
+}
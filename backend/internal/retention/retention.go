@@ -0,0 +1,74 @@
+package retention
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/config"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+// FileRemover deletes a purged material's file from storage; see
+// storage.Local. A nil FileRemover leaves every file in place.
+type FileRemover func(ctx context.Context, fileURL string) error
+
+type Job struct {
+	model       models.RetentionRepository
+	gracePeriod time.Duration
+	anonymize   bool
+	interval    time.Duration
+	removeFile  FileRemover
+	logger      *slog.Logger
+}
+
+func NewJob(model models.RetentionRepository, cfg config.RetentionConfig, removeFile FileRemover, logger *slog.Logger) *Job {
+	return &Job{
+		model:       model,
+		gracePeriod: time.Duration(cfg.GracePeriodDays) * 24 * time.Hour,
+		anonymize:   cfg.DeletedUserContent == "anonymize",
+		interval:    time.Duration(cfg.IntervalMinutes) * time.Minute,
+		removeFile:  removeFile,
+		logger:      logger,
+	}
+}
+
+// Run purges expired rows every interval until ctx is cancelled. A
+// non-positive interval disables the job.
+func (j *Job) Run(ctx context.Context) {
+	if j.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		j.PurgeOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeOnce removes rows soft-deleted longer than the grace period, then
+// their files. Rows go first so a failed file removal leaves an orphaned file
+// rather than a row pointing at nothing.
+func (j *Job) PurgeOnce(ctx context.Context) {
+	result, err := j.model.Purge(time.Now().Add(-j.gracePeriod), j.anonymize)
+	if err != nil {
+		j.logger.ErrorContext(ctx, "retention purge failed", "error", err)
+		return
+	}
+	if result.Users > 0 || result.Anonymized > 0 || result.Materials > 0 || result.Tokens > 0 {
+		j.logger.InfoContext(ctx, "retention purge completed", "users", result.Users, "anonymized", result.Anonymized, "materials", result.Materials, "tokens", result.Tokens)
+	}
+	if j.removeFile == nil {
+		return
+	}
+	for _, fileURL := range result.FileURLs {
+		if err := j.removeFile(ctx, fileURL); err != nil {
+			j.logger.ErrorContext(ctx, "file removal failed", "file_url", fileURL, "error", err)
+		}
+	}
+}
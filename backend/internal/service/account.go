@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+	"github.com/ISKOnnect/iskonnect-web/internal/utils"
+)
+
+// Export is a user's personal data as returned by AccountService.Export.
+type Export struct {
+	User    *models.User
+	Privacy *models.PrivacySettings
+	Badges  []*models.Badge
+	*models.UserActivity
+}
+
+// AccountService covers the data-subject rights: exporting one's data and
+// deleting one's account.
+type AccountService struct {
+	users       models.UserRepository
+	exports     models.ExportRepository
+	gracePeriod time.Duration
+}
+
+// NewAccountService returns an AccountService. gracePeriod is how long a
+// deleted account is kept before the retention job purges it.
+func NewAccountService(users models.UserRepository, exports models.ExportRepository, gracePeriod time.Duration) *AccountService {
+	return &AccountService{users: users, exports: exports, gracePeriod: gracePeriod}
+}
+
+func (s *AccountService) Export(ctx context.Context, userID int) (*Export, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, notFound(err, "User not found", "Export failed")
+	}
+	privacy, err := s.users.GetPrivacySettings(ctx, userID)
+	if err != nil {
+		return nil, apierror.Internal("Export failed", err)
+	}
+	badges, err := s.users.GetBadges(ctx, userID)
+	if err != nil {
+		return nil, apierror.Internal("Export failed", err)
+	}
+	activity, err := s.exports.Activity(ctx, userID)
+	if err != nil {
+		return nil, apierror.Internal("Export failed", err)
+	}
+	return &Export{User: user, Privacy: privacy, Badges: badges, UserActivity: activity}, nil
+}
+
+// Delete soft-deletes the account after checking the password, which ends
+// every session at once. The account can be restored by an admin until the
+// returned time, when the retention job purges or anonymizes it.
+func (s *AccountService) Delete(ctx context.Context, userID int, password string) (time.Time, error) {
+	hash, err := s.users.GetPasswordHash(ctx, userID)
+	if err != nil {
+		return time.Time{}, notFound(err, "User not found", "Delete failed")
+	}
+	if utils.CheckPassword(hash, password) != nil {
+		return time.Time{}, apierror.Invalid("password", "Incorrect password")
+	}
+	if err := s.users.Delete(ctx, userID); err != nil {
+		return time.Time{}, notFound(err, "User not found", "Delete failed")
+	}
+	return time.Now().Add(s.gracePeriod), nil
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+var mentionPattern = regexp.MustCompile(`(?:^|\s)@([a-zA-Z0-9_]{3,30})\b`)
+
+// CommentNotice is an email a new or edited comment owes one user.
+type CommentNotice struct {
+	To            string
+	Heading       string
+	MaterialTitle string
+	Author        string
+	Body          string
+}
+
+// CommentService runs the comment threads of materials. Every method first
+// checks the material is visible, so hidden and deleted materials can't be
+// read or discussed through their comments.
+type CommentService struct {
+	comments  models.CommentRepository
+	materials *MaterialService
+	users     models.UserRepository
+}
+
+func NewCommentService(comments models.CommentRepository, materials *MaterialService, users models.UserRepository) *CommentService {
+	return &CommentService{comments: comments, materials: materials, users: users}
+}
+
+func validateCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" || len(body) > 2000 {
+		return "", apierror.Invalid("body", "Comment must be 1-2000 characters")
+	}
+	return body, nil
+}
+
+// comment loads comment id and checks that it belongs to the visible
+// material materialID.
+func (s *CommentService) comment(ctx context.Context, materialID, id int) (*models.Material, *models.Comment, error) {
+	material, err := s.materials.Get(ctx, materialID)
+	if err != nil {
+		return nil, nil, err
+	}
+	comment, err := s.comments.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, notFound(err, "Comment not found", "Failed to get comment")
+	}
+	if comment.MaterialID != material.ID {
+		return nil, nil, apierror.NotFound("Comment not found")
+	}
+	return material, comment, nil
+}
+
+// List returns one page of top-level comments on a visible material and
+// their total.
+func (s *CommentService) List(ctx context.Context, materialID, limit, offset int) ([]*models.Comment, int, error) {
+	if _, err := s.materials.Get(ctx, materialID); err != nil {
+		return nil, 0, err
+	}
+	comments, total, err := s.comments.ListByMaterial(ctx, materialID, limit, offset)
+	if err != nil {
+		return nil, 0, apierror.Internal("Failed to list comments", err)
+	}
+	return comments, total, nil
+}
+
+// ListReplies returns one page of direct replies to a comment and their
+// total.
+func (s *CommentService) ListReplies(ctx context.Context, materialID, parentID, limit, offset int) ([]*models.Comment, int, error) {
+	if _, _, err := s.comment(ctx, materialID, parentID); err != nil {
+		return nil, 0, err
+	}
+	replies, total, err := s.comments.ListReplies(ctx, parentID, limit, offset)
+	if err != nil {
+		return nil, 0, apierror.Internal("Failed to list replies", err)
+	}
+	return replies, total, nil
+}
+
+// Create posts a comment, or a reply if parentID is set, and returns it with
+// the emails owed to the uploader and the mentioned users.
+func (s *CommentService) Create(ctx context.Context, materialID, authorID int, body string, parentID *int) (*models.Comment, []CommentNotice, error) {
+	material, err := s.materials.Get(ctx, materialID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if body, err = validateCommentBody(body); err != nil {
+		return nil, nil, err
+	}
+	if parentID != nil {
+		parent, err := s.comments.GetByID(ctx, *parentID)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, apierror.Invalid("parent_id", "Parent comment not found")
+		case err != nil:
+			return nil, nil, apierror.Internal("Failed to get comment", err)
+		case parent.MaterialID != materialID || parent.Deleted:
+			return nil, nil, apierror.Invalid("parent_id", "Parent comment not found")
+		}
+	}
+
+	mentioned, err := s.mentionedUsers(ctx, body, authorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	comment := &models.Comment{
+		MaterialID: materialID,
+		ParentID:   parentID,
+		AuthorID:   authorID,
+		Body:       body,
+	}
+	for _, u := range mentioned {
+		comment.MentionIDs = append(comment.MentionIDs, u.ID)
+	}
+	if err := s.comments.Create(ctx, comment); err != nil {
+		return nil, nil, apierror.Internal("Create failed", err)
+	}
+	created, err := s.comments.GetByID(ctx, comment.ID)
+	if err != nil {
+		return nil, nil, apierror.Internal("Failed to fetch comment", err)
+	}
+	return created, s.notices(ctx, material, created, mentioned, true), nil
+}
+
+// Update replaces the body of the author's own comment. Only users the edit
+// newly mentions are owed an email.
+func (s *CommentService) Update(ctx context.Context, materialID, id, userID int, body string) (*models.Comment, []CommentNotice, error) {
+	material, comment, err := s.comment(ctx, materialID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if comment.AuthorID != userID {
+		return nil, nil, apierror.Forbidden("Forbidden: not your comment")
+	}
+	if comment.Deleted {
+		return nil, nil, apierror.Conflict("Comment has been deleted")
+	}
+	if body, err = validateCommentBody(body); err != nil {
+		return nil, nil, err
+	}
+
+	mentioned, err := s.mentionedUsers(ctx, body, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	mentionIDs := make([]int, 0, len(mentioned))
+	var added []*models.User
+	for _, u := range mentioned {
+		mentionIDs = append(mentionIDs, u.ID)
+		if !slices.Contains(comment.MentionIDs, u.ID) {
+			added = append(added, u)
+		}
+	}
+
+	if err := s.comments.Update(ctx, comment.ID, body, mentionIDs); err != nil {
+		return nil, nil, apierror.Internal("Update failed", err)
+	}
+	updated, err := s.comments.GetByID(ctx, comment.ID)
+	if err != nil {
+		return nil, nil, apierror.Internal("Failed to fetch comment", err)
+	}
+	return updated, s.notices(ctx, material, updated, added, false), nil
+}
+
+// Delete soft-deletes the author's own comment, keeping its place in the
+// thread.
+func (s *CommentService) Delete(ctx context.Context, materialID, id, userID int) error {
+	_, comment, err := s.comment(ctx, materialID, id)
+	if err != nil {
+		return err
+	}
+	if comment.AuthorID != userID {
+		return apierror.Forbidden("Forbidden: not your comment")
+	}
+	if err := s.comments.SoftDelete(ctx, comment.ID); err != nil {
+		return apierror.Internal("Delete failed", err)
+	}
+	return nil
+}
+
+// History returns the earlier bodies of a comment that hasn't been deleted.
+func (s *CommentService) History(ctx context.Context, materialID, id int) ([]*models.CommentEdit, error) {
+	_, comment, err := s.comment(ctx, materialID, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.Deleted {
+		return nil, apierror.NotFound("Comment not found")
+	}
+	edits, err := s.comments.GetHistory(ctx, comment.ID)
+	if err != nil {
+		return nil, apierror.Internal("Failed to get history", err)
+	}
+	return edits, nil
+}
+
+// mentionedUsers resolves @handle mentions in body, skipping the author.
+func (s *CommentService) mentionedUsers(ctx context.Context, body string, authorID int) ([]*models.User, error) {
+	matches := mentionPattern.FindAllStringSubmatch(body, -1)
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	handles := make([]string, 0, len(matches))
+	for _, m := range matches {
+		handles = append(handles, m[1])
+	}
+	users, err := s.users.GetByHandles(ctx, handles)
+	if err != nil {
+		return nil, apierror.Internal("Internal error", err)
+	}
+	mentioned := users[:0]
+	for _, u := range users {
+		if u.ID != authorID {
+			mentioned = append(mentioned, u)
+		}
+	}
+	return mentioned, nil
+}
+
+// notices lists one email per user to tell about comment: the material's
+// uploader if toUploader is set, then the mentioned users, never the author
+// and never anyone twice. An uploader who can't be loaded is skipped.
+func (s *CommentService) notices(ctx context.Context, material *models.Material, comment *models.Comment, mentioned []*models.User, toUploader bool) []CommentNotice {
+	var notices []CommentNotice
+	add := func(to, heading string) {
+		notices = append(notices, CommentNotice{
+			To:            to,
+			Heading:       heading,
+			MaterialTitle: material.Title,
+			Author:        comment.Author.DisplayName,
+			Body:          comment.Body,
+		})
+	}
+	notified := map[int]bool{comment.AuthorID: true}
+	if toUploader && !notified[material.UploaderID] {
+		notified[material.UploaderID] = true
+		if uploader, err := s.users.GetByID(ctx, material.UploaderID); err == nil {
+			add(uploader.Email, "New comment")
+		}
+	}
+	for _, u := range mentioned {
+		if !notified[u.ID] {
+			notified[u.ID] = true
+			add(u.Email, "You were mentioned")
+		}
+	}
+	return notices
+}
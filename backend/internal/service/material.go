@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+// UploadPoints is awarded to the uploader of each new material.
+const UploadPoints = 5
+
+var fileURLPattern = regexp.MustCompile(`^https?://`)
+
+type MaterialService struct {
+	materials models.MaterialRepository
+	users     models.UserRepository
+	calendar  models.Calendar
+}
+
+func NewMaterialService(materials models.MaterialRepository, users models.UserRepository, calendar models.Calendar) *MaterialService {
+	return &MaterialService{materials: materials, users: users, calendar: calendar}
+}
+
+// List returns one page of visible materials and their total. A limit of
+// zero returns every one.
+func (s *MaterialService) List(ctx context.Context, limit, offset int) ([]*models.Material, int, error) {
+	materials, total, err := s.materials.List(ctx, limit, offset)
+	if err != nil {
+		return nil, 0, apierror.Internal("Failed to list materials", err)
+	}
+	return materials, total, nil
+}
+
+// Get returns a material unless it is deleted or hidden by a moderator.
+func (s *MaterialService) Get(ctx context.Context, id int) (*models.Material, error) {
+	material, err := s.materials.GetByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "Material not found", "Failed to get material")
+	}
+	if material.Hidden {
+		return nil, apierror.NotFound("Material not found")
+	}
+	return material, nil
+}
+
+// Upload creates the material for uploaderID and awards UploadPoints in the
+// same transaction. It returns the uploader with the new points total.
+func (s *MaterialService) Upload(ctx context.Context, uploaderID int, material *models.Material) (*models.User, error) {
+	if _, err := s.users.GetByID(ctx, uploaderID); err != nil {
+		return nil, notFound(err, "User not found", "Failed to get user")
+	}
+	if err := validateMaterial(material); err != nil {
+		return nil, err
+	}
+	material.UploaderID = uploaderID
+	if err := s.materials.CreateWithPoints(ctx, material, UploadPoints); err != nil {
+		return nil, apierror.Internal("Create failed", err)
+	}
+	user, err := s.users.GetByID(ctx, uploaderID)
+	if err != nil {
+		return nil, apierror.Internal("Failed to fetch updated user", err)
+	}
+	return user, nil
+}
+
+// Vote records an UPVOTE or DOWNVOTE, case-insensitively, on a visible
+// material and returns the material with its new count.
+func (s *MaterialService) Vote(ctx context.Context, id, userID int, voteType string) (*models.Material, error) {
+	voteType = strings.ToUpper(voteType)
+	if voteType != "UPVOTE" && voteType != "DOWNVOTE" {
+		return nil, apierror.Invalid("vote_type", "Invalid vote type")
+	}
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := s.materials.Vote(ctx, id, userID, voteType); err != nil {
+		return nil, apierror.Internal("Vote failed", err)
+	}
+	material, err := s.Get(ctx, id)
+	if err != nil {
+		// Hidden or deleted between the vote and the reload.
+		return nil, err
+	}
+	return material, nil
+}
+
+// Bookmark saves a visible material for userID.
+func (s *MaterialService) Bookmark(ctx context.Context, id, userID int) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+	if err := s.materials.Bookmark(ctx, id, userID); err != nil {
+		return apierror.Internal("Bookmark failed", err)
+	}
+	return nil
+}
+
+func (s *MaterialService) Bookmarks(ctx context.Context, userID, limit, offset int) ([]*models.Material, int, error) {
+	bookmarks, total, err := s.materials.GetBookmarks(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, 0, apierror.Internal("Failed to get bookmarks", err)
+	}
+	return bookmarks, total, nil
+}
+
+// Leaderboard ranks users by points in filter's window, defaulting to all
+// time and 10 entries. It returns the filter as applied and the viewer's
+// own entry.
+func (s *MaterialService) Leaderboard(ctx context.Context, filter models.LeaderboardFilter, viewerID int) (models.LeaderboardFilter, []*models.LeaderboardEntry, *models.LeaderboardEntry, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+	if filter.Window == "" {
+		filter.Window = models.LeaderboardAllTime
+	}
+	start, ok, err := s.calendar.WindowStart(filter.Window, time.Now())
+	if err != nil {
+		return filter, nil, nil, apierror.Invalid("window", "Invalid window (week, month, semester, all)")
+	}
+	if ok {
+		filter.Since = start
+	}
+	entries, me, err := s.users.GetLeaderboard(ctx, filter, viewerID)
+	if err != nil {
+		return filter, nil, nil, apierror.Internal("Failed to get leaderboard", err)
+	}
+	return filter, entries, me, nil
+}
+
+// ListAll returns one page of materials, including hidden ones, for admins.
+func (s *MaterialService) ListAll(ctx context.Context, limit, offset int) ([]*models.Material, int, error) {
+	materials, total, err := s.materials.ListAll(ctx, limit, offset)
+	if err != nil {
+		return nil, 0, apierror.Internal("Failed to list materials", err)
+	}
+	return materials, total, nil
+}
+
+func (s *MaterialService) ListDeleted(ctx context.Context, limit, offset int) ([]*models.Material, int, error) {
+	materials, total, err := s.materials.ListDeleted(ctx, limit, offset)
+	if err != nil {
+		return nil, 0, apierror.Internal("Failed to list materials", err)
+	}
+	return materials, total, nil
+}
+
+// Update replaces the editable fields of material id and returns the
+// material before and after the change.
+func (s *MaterialService) Update(ctx context.Context, id int, material *models.Material) (before, after *models.Material, err error) {
+	if err := validateMaterial(material); err != nil {
+		return nil, nil, err
+	}
+	before, err = s.materials.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, notFound(err, "Material not found", "Failed to get material")
+	}
+	material.ID = id
+	if err := s.materials.Update(ctx, material); err != nil {
+		return nil, nil, notFound(err, "Material not found", "Update failed")
+	}
+	after, err = s.materials.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, apierror.Internal("Failed to fetch updated material", err)
+	}
+	return before, after, nil
+}
+
+// Delete soft-deletes material id and returns it as it was.
+func (s *MaterialService) Delete(ctx context.Context, id int) (*models.Material, error) {
+	before, err := s.materials.GetByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "Material not found", "Failed to get material")
+	}
+	if err := s.materials.Delete(ctx, id); err != nil {
+		return nil, notFound(err, "Material not found", "Delete failed")
+	}
+	return before, nil
+}
+
+func (s *MaterialService) Restore(ctx context.Context, id int) error {
+	if err := s.materials.Restore(ctx, id); err != nil {
+		return notFound(err, "Deleted material not found", "Restore failed")
+	}
+	return nil
+}
+
+// validateMaterial reports every invalid field at once.
+func validateMaterial(m *models.Material) error {
+	var fields []apierror.FieldError
+	check := func(field string, ok bool, message string) {
+		if !ok {
+			fields = append(fields, apierror.FieldError{Field: field, Message: message})
+		}
+	}
+	check("title", strings.TrimSpace(m.Title) != "" && len(m.Title) <= 100, "must be 1-100 characters")
+	check("description", strings.TrimSpace(m.Description) != "" && len(m.Description) <= 500, "must be 1-500 characters")
+	check("subject", strings.TrimSpace(m.Subject) != "" && len(m.Subject) <= 50, "must be 1-50 characters")
+	check("college", strings.TrimSpace(m.College) != "" && len(m.College) <= 50, "must be 1-50 characters")
+	check("course", strings.TrimSpace(m.Course) != "" && len(m.Course) <= 50, "must be 1-50 characters")
+	check("file_url", fileURLPattern.MatchString(m.FileURL), "must be an http or https URL")
+	if len(fields) > 0 {
+		return apierror.Validation("Invalid material", fields...)
+	}
+	return nil
+}
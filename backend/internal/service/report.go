@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+// MaxBulkReports caps how many reports one bulk moderation action touches.
+const MaxBulkReports = 100
+
+// ReportService files material reports and runs the moderation queue.
+type ReportService struct {
+	reports   models.ReportRepository
+	materials *MaterialService
+}
+
+func NewReportService(reports models.ReportRepository, materials *MaterialService) *ReportService {
+	return &ReportService{reports: reports, materials: materials}
+}
+
+// Report files reporterID's report against a visible material and returns
+// its ID. Each user can have one report per material.
+func (s *ReportService) Report(ctx context.Context, materialID, reporterID int, reason, details string) (int, error) {
+	reason = strings.ToLower(strings.TrimSpace(reason))
+	details = strings.TrimSpace(details)
+	if !models.IsValidReportReason(reason) {
+		return 0, apierror.Invalid("reason", "Invalid reason (copyright, wrong_subject, spam, inappropriate, other)")
+	}
+	if len(details) > 1000 {
+		return 0, apierror.Invalid("details", "Details must be at most 1000 characters")
+	}
+	if _, err := s.materials.Get(ctx, materialID); err != nil {
+		return 0, err
+	}
+
+	id, err := s.reports.Create(ctx, materialID, reporterID, reason, details)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, apierror.Conflict("You already reported this material")
+	}
+	if err != nil {
+		return 0, apierror.Internal("Report failed", err)
+	}
+	return id, nil
+}
+
+// List returns one page of reports matching filter and their total. An
+// empty status lists open reports and "all" lists every status.
+func (s *ReportService) List(ctx context.Context, filter models.ReportFilter) ([]*models.Report, int, error) {
+	switch filter.Status {
+	case "":
+		filter.Status = models.ReportOpen
+	case "all":
+		filter.Status = ""
+	}
+	reports, total, err := s.reports.List(ctx, filter)
+	if err != nil {
+		return nil, 0, apierror.Internal("Failed to list reports", err)
+	}
+	return reports, total, nil
+}
+
+// SetStatus changes a report's triage state.
+func (s *ReportService) SetStatus(ctx context.Context, id int, status string, moderatorID int) error {
+	switch status {
+	case models.ReportOpen, models.ReportReviewing, models.ReportResolved, models.ReportDismissed:
+	default:
+		return apierror.Invalid("status", "Invalid status (open, reviewing, resolved, dismissed)")
+	}
+	if err := s.reports.SetStatus(ctx, id, status, moderatorID); err != nil {
+		return notFound(err, "Report not found", "Update failed")
+	}
+	return nil
+}
+
+// Apply runs action on each report in ids independently. It returns the IDs
+// that succeeded and, for the rest, why they failed.
+func (s *ReportService) Apply(ctx context.Context, action string, ids []int, moderatorID int) ([]int, map[int]string, error) {
+	switch action {
+	case models.ModerationHide, models.ModerationRestore, models.ModerationDelete, models.ModerationDismiss:
+	default:
+		return nil, nil, apierror.Invalid("action", "Invalid action (hide, restore, delete, dismiss)")
+	}
+	if len(ids) == 0 || len(ids) > MaxBulkReports {
+		return nil, nil, apierror.Invalid("report_ids", "report_ids must contain 1-100 IDs")
+	}
+
+	processed := []int{}
+	failed := map[int]string{}
+	for _, id := range ids {
+		err := s.reports.Apply(ctx, id, action, moderatorID)
+		switch {
+		case err == nil:
+			processed = append(processed, id)
+		case errors.Is(err, sql.ErrNoRows):
+			failed[id] = "not found"
+		case errors.Is(err, models.ErrMaterialGone):
+			failed[id] = err.Error()
+		default:
+			failed[id] = "action failed"
+		}
+	}
+	return processed, failed, nil
+}
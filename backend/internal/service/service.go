@@ -0,0 +1,21 @@
+// Package service holds the business rules between the HTTP handlers and
+// the model repositories. Services validate input and translate storage
+// errors into apierror values, so handlers only decode requests and render
+// results.
+package service
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+)
+
+// notFound maps sql.ErrNoRows to a 404 with message and anything else to a
+// 500 with internal.
+func notFound(err error, message, internal string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return apierror.NotFound(message)
+	}
+	return apierror.Internal(internal, err)
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+	"github.com/ISKOnnect/iskonnect-web/internal/utils"
+)
+
+const (
+	maxTokensPerUser      = 20
+	defaultTokenLifetime  = 90
+	maxTokenLifetimeDays  = 365
+	tokenDisplayPrefixLen = len(models.APITokenPrefix) + 6
+)
+
+// TokenRequest describes a personal access token to create. A zero
+// ExpiresInDays means the default lifetime.
+type TokenRequest struct {
+	Name          string
+	Scopes        []string
+	ExpiresInDays int
+}
+
+type TokenService struct {
+	tokens models.APITokenRepository
+}
+
+func NewTokenService(tokens models.APITokenRepository) *TokenService {
+	return &TokenService{tokens: tokens}
+}
+
+func (s *TokenService) List(ctx context.Context, userID int) ([]*models.APIToken, error) {
+	tokens, err := s.tokens.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, apierror.Internal("Failed to list tokens", err)
+	}
+	return tokens, nil
+}
+
+// Create issues a token for userID. The secret is returned only here; just
+// its hash is stored.
+func (s *TokenService) Create(ctx context.Context, userID int, req TokenRequest) (string, *models.APIToken, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.ExpiresInDays == 0 {
+		req.ExpiresInDays = defaultTokenLifetime
+	}
+	var fields []apierror.FieldError
+	if req.Name == "" || len(req.Name) > 100 {
+		fields = append(fields, apierror.FieldError{Field: "name", Message: "must be 1 to 100 characters"})
+	}
+	if len(req.Scopes) == 0 {
+		fields = append(fields, apierror.FieldError{Field: "scopes", Message: "must not be empty"})
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(models.Scopes, scope) {
+			fields = append(fields, apierror.FieldError{Field: "scopes", Message: "must be one of " + strings.Join(models.Scopes, ", ")})
+			break
+		}
+	}
+	if req.ExpiresInDays < 1 || req.ExpiresInDays > maxTokenLifetimeDays {
+		fields = append(fields, apierror.FieldError{Field: "expires_in_days", Message: "must be between 1 and 365"})
+	}
+	if len(fields) > 0 {
+		return "", nil, apierror.Validation("Invalid token request", fields...)
+	}
+
+	count, err := s.tokens.CountByUser(ctx, userID)
+	if err != nil {
+		return "", nil, apierror.Internal("Failed to create token", err)
+	}
+	if count >= maxTokensPerUser {
+		return "", nil, apierror.Conflict("Token limit reached; revoke an unused token first")
+	}
+
+	random, err := utils.GenerateRandomToken(40)
+	if err != nil {
+		return "", nil, apierror.Internal("Failed to create token", err)
+	}
+	secret := models.APITokenPrefix + random
+	slices.Sort(req.Scopes)
+	token := &models.APIToken{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    secret[:tokenDisplayPrefixLen],
+		Scopes:    slices.Compact(req.Scopes),
+		ExpiresAt: time.Now().AddDate(0, 0, req.ExpiresInDays),
+	}
+	if err := s.tokens.Create(ctx, token, utils.HashToken(secret)); err != nil {
+		return "", nil, apierror.Internal("Failed to create token", err)
+	}
+	return secret, token, nil
+}
+
+func (s *TokenService) Revoke(ctx context.Context, userID, id int) error {
+	if err := s.tokens.Delete(ctx, userID, id); err != nil {
+		return notFound(err, "Token not found", "Failed to revoke token")
+	}
+	return nil
+}
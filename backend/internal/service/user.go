@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/apierror"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+)
+
+var handlePattern = regexp.MustCompile(`^[a-zA-Z0-9_]{3,30}$`)
+
+// ProfileUpdate holds the fields a user may change on their own account.
+type ProfileUpdate struct {
+	FirstName string
+	LastName  string
+	College   string
+	Course    string
+}
+
+type UserService struct {
+	users     models.UserRepository
+	materials models.MaterialRepository
+}
+
+func NewUserService(users models.UserRepository, materials models.MaterialRepository) *UserService {
+	return &UserService{users: users, materials: materials}
+}
+
+func (s *UserService) Get(ctx context.Context, id int) (*models.User, error) {
+	user, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "User not found", "Failed to get user")
+	}
+	return user, nil
+}
+
+// UpdateProfile trims and validates u and saves it to user id.
+func (s *UserService) UpdateProfile(ctx context.Context, id int, u ProfileUpdate) (*models.User, error) {
+	user, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "User not found", "Failed to get user")
+	}
+	user.FirstName = strings.TrimSpace(u.FirstName)
+	user.LastName = strings.TrimSpace(u.LastName)
+	user.College = strings.TrimSpace(u.College)
+	user.Course = strings.TrimSpace(u.Course)
+	var fields []apierror.FieldError
+	if len(user.College) > 50 {
+		fields = append(fields, apierror.FieldError{Field: "college", Message: "must be at most 50 characters"})
+	}
+	if len(user.Course) > 50 {
+		fields = append(fields, apierror.FieldError{Field: "course", Message: "must be at most 50 characters"})
+	}
+	if len(fields) > 0 {
+		return nil, apierror.Validation("College and course must be at most 50 characters", fields...)
+	}
+	if err := s.users.Update(ctx, user); err != nil {
+		return nil, notFound(err, "User not found", "Update failed")
+	}
+	return user, nil
+}
+
+func (s *UserService) Privacy(ctx context.Context, id int) (*models.PrivacySettings, error) {
+	settings, err := s.users.GetPrivacySettings(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "User not found", "Failed to get privacy settings")
+	}
+	return settings, nil
+}
+
+// UpdatePrivacy validates the handle, which must be unique and is required
+// to hide the real name, and saves settings for user id.
+func (s *UserService) UpdatePrivacy(ctx context.Context, id int, settings *models.PrivacySettings) error {
+	settings.Handle = strings.TrimSpace(settings.Handle)
+	if settings.Handle != "" && !handlePattern.MatchString(settings.Handle) {
+		return apierror.Invalid("handle", "Handle must be 3-30 letters, numbers or underscores")
+	}
+	if settings.HideRealName && settings.Handle == "" {
+		return apierror.Invalid("handle", "A handle is required to hide your real name")
+	}
+	if settings.Handle != "" {
+		taken, err := s.users.IsHandleTaken(ctx, settings.Handle, id)
+		if err != nil {
+			return apierror.Internal("Update failed", err)
+		}
+		if taken {
+			return apierror.Conflict("Handle already taken")
+		}
+	}
+	err := s.users.UpdatePrivacySettings(ctx, id, settings)
+	if errors.Is(err, models.ErrHandleTaken) {
+		// Claimed by someone else since the check above.
+		return apierror.Conflict("Handle already taken")
+	}
+	if err != nil {
+		return apierror.Internal("Update failed", err)
+	}
+	return nil
+}
+
+// PublicProfile returns user id's profile as viewerID sees it. Uploads are
+// left out when the user hides their activity from others.
+func (s *UserService) PublicProfile(ctx context.Context, id, viewerID int) (*models.PublicProfile, error) {
+	profile, err := s.users.GetPublicProfile(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "User not found", "Failed to get profile")
+	}
+	if !profile.HideActivity || viewerID == id {
+		if profile.Uploads, err = s.materials.ListByUploader(ctx, id); err != nil {
+			return nil, apierror.Internal("Failed to get profile", err)
+		}
+	}
+	return profile, nil
+}
+
+func (s *UserService) List(ctx context.Context, limit, offset int) ([]*models.User, int, error) {
+	users, total, err := s.users.GetAll(ctx, limit, offset)
+	if err != nil {
+		return nil, 0, apierror.Internal("Failed to get users", err)
+	}
+	return users, total, nil
+}
+
+func (s *UserService) ListDeleted(ctx context.Context, limit, offset int) ([]*models.User, int, error) {
+	users, total, err := s.users.GetDeleted(ctx, limit, offset)
+	if err != nil {
+		return nil, 0, apierror.Internal("Failed to get users", err)
+	}
+	return users, total, nil
+}
+
+// Delete soft-deletes user id and returns the user as they were.
+func (s *UserService) Delete(ctx context.Context, id int) (*models.User, error) {
+	before, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "User not found", "Failed to get user")
+	}
+	if err := s.users.Delete(ctx, id); err != nil {
+		return nil, notFound(err, "User not found", "Delete failed")
+	}
+	return before, nil
+}
+
+func (s *UserService) Restore(ctx context.Context, id int) error {
+	if err := s.users.Restore(ctx, id); err != nil {
+		return notFound(err, "Deleted user not found", "Restore failed")
+	}
+	return nil
+}
+
+// SetModerator grants or revokes moderator rights on student id and
+// reports whether the student was a moderator before.
+func (s *UserService) SetModerator(ctx context.Context, id int, isModerator bool) (was bool, err error) {
+	before, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		return false, notFound(err, "Student not found", "Failed to get user")
+	}
+	if err := s.users.SetModerator(ctx, id, isModerator); err != nil {
+		return false, notFound(err, "Student not found", "Update failed")
+	}
+	return before.IsModerator, nil
+}
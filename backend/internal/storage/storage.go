@@ -0,0 +1,51 @@
+// Package storage maps material file URLs to files the server manages.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/config"
+)
+
+// Local is a directory served under a public URL prefix. Files behind any
+// other URL are hosted elsewhere, and Local leaves them alone.
+type Local struct {
+	publicURL string
+	dir       string
+}
+
+func NewLocal(cfg config.StorageConfig) *Local {
+	return &Local{publicURL: strings.TrimSuffix(cfg.PublicURL, "/") + "/", dir: cfg.Dir}
+}
+
+// Path returns the file behind fileURL, and false if the URL is not served
+// from this directory.
+func (l *Local) Path(fileURL string) (string, bool) {
+	if l.dir == "" || !strings.HasPrefix(fileURL, l.publicURL) {
+		return "", false
+	}
+	rel := filepath.Clean(filepath.FromSlash(strings.TrimPrefix(fileURL, l.publicURL)))
+	if rel == "." || !filepath.IsLocal(rel) {
+		return "", false
+	}
+	return filepath.Join(l.dir, rel), true
+}
+
+// Remove deletes the file behind fileURL. URLs hosted elsewhere and files
+// already gone are not errors. It matches retention.FileRemover.
+func (l *Local) Remove(ctx context.Context, fileURL string) error {
+	path, ok := l.Path(fileURL)
+	if !ok {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove %s: %w", path, err)
+	}
+	return nil
+}
@@ -1,41 +1,168 @@
 package utils
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"math/big"
+	"os"
+	"sort"
 	"strconv"
 	"time"
 
+	"github.com/ISKOnnect/iskonnect-web/internal/config"
 	"github.com/ISKOnnect/iskonnect-web/internal/models"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// LegacyKeyID names the HS256 key built from config.JWTConfig.Secret. Tokens
+// without a kid header were signed with it.
+const LegacyKeyID = "default"
+
 type JWTClaims struct {
-	UserID    int  `json:"user_id"`
-	IsStudent bool `json:"is_student"`
+	UserID      int  `json:"user_id"`
+	IsStudent   bool `json:"is_student"`
+	IsModerator bool `json:"is_moderator,omitempty"`
+	// SessionVersion must match the user's current version; bumping it
+	// revokes the token.
+	SessionVersion int `json:"sv,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(user *models.User, secret string, expiryHours int) (string, error) {
+type signingKey struct {
+	id     string
+	method jwt.SigningMethod
+	// sign is nil for verify-only keys.
+	sign   crypto.PrivateKey
+	verify crypto.PublicKey
+	// public is false for HMAC keys, which must never be published.
+	public bool
+}
+
+// Keyring signs tokens with one key and verifies them with any configured
+// key, so a new key can be rolled out while tokens signed by the old one are
+// still accepted.
+type Keyring struct {
+	signer   *signingKey
+	keys     map[string]*signingKey
+	issuer   string
+	audience string
+}
+
+// NewKeyring loads the keys in cfg. Secret, when set, becomes the HS256 key
+// LegacyKeyID.
+func NewKeyring(cfg config.JWTConfig) (*Keyring, error) {
+	k := &Keyring{keys: map[string]*signingKey{}, issuer: cfg.Issuer, audience: cfg.Audience}
+	if cfg.Secret != "" {
+		k.keys[LegacyKeyID] = hmacKey(LegacyKeyID, cfg.Secret)
+	}
+	for _, kc := range cfg.Keys {
+		if _, ok := k.keys[kc.ID]; ok {
+			return nil, fmt.Errorf("jwt key %q is defined twice", kc.ID)
+		}
+		key, err := loadKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", kc.ID, err)
+		}
+		k.keys[kc.ID] = key
+	}
+
+	id := cfg.SigningKey
+	if id == "" {
+		id = LegacyKeyID
+	}
+	k.signer = k.keys[id]
+	if k.signer == nil {
+		return nil, fmt.Errorf("jwt signing key %q is not configured", id)
+	}
+	if k.signer.sign == nil {
+		return nil, fmt.Errorf("jwt signing key %q has no private key", id)
+	}
+	return k, nil
+}
+
+func hmacKey(id, secret string) *signingKey {
+	return &signingKey{id: id, method: jwt.SigningMethodHS256, sign: []byte(secret), verify: []byte(secret)}
+}
+
+// loadKey reads a PEM key file. A private key can both sign and verify; a
+// public key only verifies.
+func loadKey(kc config.JWTKey) (*signingKey, error) {
+	if kc.Algorithm == "HS256" {
+		if kc.Secret == "" {
+			return nil, errors.New("HS256 keys need a secret")
+		}
+		return hmacKey(kc.ID, kc.Secret), nil
+	}
+
+	pem, err := os.ReadFile(kc.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	key := &signingKey{id: kc.ID, public: true}
+	switch kc.Algorithm {
+	case "RS256":
+		key.method = jwt.SigningMethodRS256
+		if priv, err := jwt.ParseRSAPrivateKeyFromPEM(pem); err == nil {
+			key.sign, key.verify = priv, &priv.PublicKey
+		} else if key.verify, err = jwt.ParseRSAPublicKeyFromPEM(pem); err != nil {
+			return nil, fmt.Errorf("%s holds no RSA key", kc.KeyFile)
+		}
+	case "EdDSA":
+		key.method = jwt.SigningMethodEdDSA
+		if priv, err := jwt.ParseEdPrivateKeyFromPEM(pem); err == nil {
+			key.sign, key.verify = priv, priv.(ed25519.PrivateKey).Public()
+		} else if key.verify, err = jwt.ParseEdPublicKeyFromPEM(pem); err != nil {
+			return nil, fmt.Errorf("%s holds no Ed25519 key", kc.KeyFile)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", kc.Algorithm)
+	}
+	return key, nil
+}
+
+func (k *Keyring) GenerateJWT(user *models.User, expiryHours int) (string, error) {
+	now := time.Now()
 	claims := &JWTClaims{
-		UserID:    user.ID,
-		IsStudent: user.IsStudent,
+		UserID:         user.ID,
+		IsStudent:      user.IsStudent,
+		IsModerator:    user.IsModerator,
+		SessionVersion: user.SessionVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(expiryHours) * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    k.issuer,
+			Audience:  jwt.ClaimStrings{k.audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(expiryHours) * time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Subject:   strconv.Itoa(user.ID),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	token := jwt.NewWithClaims(k.signer.method, claims)
+	token.Header["kid"] = k.signer.id
+	return token.SignedString(k.signer.sign)
 }
 
-func ValidateJWT(tokenString, secret string) (*JWTClaims, error) {
+// ValidateJWT checks the signature against the key named by the kid header,
+// then the expiry, issuer and audience.
+func (k *Keyring) ValidateJWT(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method")
+		id, _ := token.Header["kid"].(string)
+		if id == "" {
+			id = LegacyKeyID
+		}
+		key := k.keys[id]
+		if key == nil {
+			return nil, fmt.Errorf("unknown key %q", id)
+		}
+		// The algorithm is fixed per key, so a token can't pick HS256 to
+		// have a published RSA key used as an HMAC secret.
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
 		}
-		return []byte(secret), nil
-	})
+		return key.verify, nil
+	}, jwt.WithIssuer(k.issuer), jwt.WithAudience(k.audience), jwt.WithExpirationRequired())
 	if err != nil {
 		return nil, err
 	}
@@ -44,3 +171,43 @@ func ValidateJWT(tokenString, secret string) (*JWTClaims, error) {
 	}
 	return nil, fmt.Errorf("invalid token")
 }
+
+// JWK is one public key in RFC 7517 form.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS lists the public halves of the asymmetric keys so other services can
+// verify our tokens. HMAC keys are left out.
+func (k *Keyring) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range k.keys {
+		if !key.public {
+			continue
+		}
+		jwk := JWK{Kid: key.id, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.verify.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty, jwk.Crv = "OKP", "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
@@ -0,0 +1,287 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ISKOnnect/iskonnect-web/internal/config"
+	"github.com/ISKOnnect/iskonnect-web/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "iskonnect"
+	testAudience = "iskonnect-api"
+	testSecret   = "legacy-secret"
+)
+
+// testKeys are freshly generated keys and the keyring config that loads
+// them. "rs" and "ed" can sign; "rs-pub" and "ed-pub" only hold the public
+// halves of rsVerify and edVerify, as for tokens signed by another service.
+type testKeys struct {
+	cfg      config.JWTConfig
+	rs       *rsa.PrivateKey
+	ed       ed25519.PrivateKey
+	rsVerify *rsa.PrivateKey
+	edVerify ed25519.PrivateKey
+	rsPEM    []byte
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+	dir := t.TempDir()
+	write := func(name, blockType string, der []byte) (string, []byte) {
+		data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path, data
+	}
+	public := func(pub interface{}) []byte {
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der
+	}
+	private := func(priv interface{}) []byte {
+		der, err := x509.MarshalPKCS8PrivateKey(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der
+	}
+
+	k := &testKeys{}
+	var err error
+	for _, key := range []**rsa.PrivateKey{&k.rs, &k.rsVerify} {
+		if *key, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, key := range []*ed25519.PrivateKey{&k.ed, &k.edVerify} {
+		if _, *key, err = ed25519.GenerateKey(rand.Reader); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rsFile, _ := write("rs.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(k.rs))
+	edFile, _ := write("ed.pem", "PRIVATE KEY", private(k.ed))
+	rsPubFile, rsPEM := write("rs-pub.pem", "PUBLIC KEY", public(&k.rsVerify.PublicKey))
+	edPubFile, _ := write("ed-pub.pem", "PUBLIC KEY", public(k.edVerify.Public()))
+	k.rsPEM = rsPEM
+
+	k.cfg = config.JWTConfig{
+		Secret: testSecret,
+		Keys: []config.JWTKey{
+			{ID: "rs", Algorithm: "RS256", KeyFile: rsFile},
+			{ID: "ed", Algorithm: "EdDSA", KeyFile: edFile},
+			{ID: "rs-pub", Algorithm: "RS256", KeyFile: rsPubFile},
+			{ID: "ed-pub", Algorithm: "EdDSA", KeyFile: edPubFile},
+			{ID: "hs", Algorithm: "HS256", Secret: "rotated-secret"},
+		},
+		Issuer:   testIssuer,
+		Audience: testAudience,
+	}
+	return k
+}
+
+func (k *testKeys) keyring(t *testing.T, signingKey string) *Keyring {
+	t.Helper()
+	cfg := k.cfg
+	cfg.SigningKey = signingKey
+	keys, err := NewKeyring(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func testClaims(iss, aud string, exp time.Time) *JWTClaims {
+	return &JWTClaims{
+		UserID:    7,
+		IsStudent: true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    iss,
+			Audience:  jwt.ClaimStrings{aud},
+			ExpiresAt: jwt.NewNumericDate(exp),
+		},
+	}
+}
+
+// sign signs claims by hand, so tests can forge headers GenerateJWT never
+// writes. An empty kid leaves the header out.
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestKeyringSignsWithEachKey(t *testing.T) {
+	k := newTestKeys(t)
+	verifier := k.keyring(t, "")
+	for _, id := range []string{LegacyKeyID, "rs", "ed", "hs"} {
+		token, err := k.keyring(t, id).GenerateJWT(&models.User{ID: 7, IsStudent: true}, 1)
+		if err != nil {
+			t.Fatalf("%s: GenerateJWT: %v", id, err)
+		}
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, &JWTClaims{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kid := parsed.Header["kid"]; kid != id {
+			t.Errorf("%s: kid = %v", id, kid)
+		}
+		claims, err := verifier.ValidateJWT(token)
+		if err != nil {
+			t.Errorf("%s: ValidateJWT: %v", id, err)
+			continue
+		}
+		if claims.UserID != 7 || claims.Issuer != testIssuer {
+			t.Errorf("%s: claims = %+v", id, claims)
+		}
+	}
+}
+
+func TestKeyringValidateJWT(t *testing.T) {
+	k := newTestKeys(t)
+	keys := k.keyring(t, "rs")
+	_, stranger, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := func() *JWTClaims { return testClaims(testIssuer, testAudience, time.Now().Add(time.Hour)) }
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"rs256", sign(t, jwt.SigningMethodRS256, "rs", k.rs, valid()), true},
+		{"eddsa", sign(t, jwt.SigningMethodEdDSA, "ed", k.ed, valid()), true},
+		{"rotated hs256", sign(t, jwt.SigningMethodHS256, "hs", []byte("rotated-secret"), valid()), true},
+		{"legacy without kid", sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), valid()), true},
+		{"verify-only rs256", sign(t, jwt.SigningMethodRS256, "rs-pub", k.rsVerify, valid()), true},
+		{"verify-only eddsa", sign(t, jwt.SigningMethodEdDSA, "ed-pub", k.edVerify, valid()), true},
+
+		// An HS256 token keyed with the published RSA key must not verify
+		// against the RSA key of the same kid.
+		{"hs256 with rsa kid", sign(t, jwt.SigningMethodHS256, "rs-pub", k.rsPEM, valid()), false},
+		{"rs256 with hmac kid", sign(t, jwt.SigningMethodRS256, LegacyKeyID, k.rs, valid()), false},
+		{"unknown kid", sign(t, jwt.SigningMethodRS256, "gone", k.rs, valid()), false},
+		{"wrong key for kid", sign(t, jwt.SigningMethodEdDSA, "ed", stranger, valid()), false},
+		{"legacy wrong secret", sign(t, jwt.SigningMethodHS256, "", []byte("guess"), valid()), false},
+		{"legacy kid is hmac only", sign(t, jwt.SigningMethodHS256, "", []byte("rotated-secret"), valid()), false},
+		{"wrong issuer", sign(t, jwt.SigningMethodRS256, "rs", k.rs, testClaims("elsewhere", testAudience, time.Now().Add(time.Hour))), false},
+		{"wrong audience", sign(t, jwt.SigningMethodRS256, "rs", k.rs, testClaims(testIssuer, "other-api", time.Now().Add(time.Hour))), false},
+		{"expired", sign(t, jwt.SigningMethodRS256, "rs", k.rs, testClaims(testIssuer, testAudience, time.Now().Add(-time.Minute))), false},
+		{"no expiry", sign(t, jwt.SigningMethodRS256, "rs", k.rs, &JWTClaims{UserID: 7, RegisteredClaims: jwt.RegisteredClaims{Issuer: testIssuer, Audience: jwt.ClaimStrings{testAudience}}}), false},
+	}
+	for _, tt := range tests {
+		claims, err := keys.ValidateJWT(tt.token)
+		switch {
+		case tt.ok && err != nil:
+			t.Errorf("%s: ValidateJWT: %v", tt.name, err)
+		case tt.ok && claims.UserID != 7:
+			t.Errorf("%s: user = %d, want 7", tt.name, claims.UserID)
+		case !tt.ok && err == nil:
+			t.Errorf("%s: ValidateJWT accepted the token", tt.name)
+		}
+	}
+}
+
+func TestNewKeyringErrors(t *testing.T) {
+	k := newTestKeys(t)
+	rsFile := k.cfg.Keys[0].KeyFile
+	edFile := k.cfg.Keys[1].KeyFile
+
+	tests := []struct {
+		name string
+		cfg  config.JWTConfig
+		want string
+	}{
+		{"verify-only signer", config.JWTConfig{Keys: k.cfg.Keys, SigningKey: "rs-pub"}, "has no private key"},
+		{"unknown signer", config.JWTConfig{Keys: k.cfg.Keys, SigningKey: "gone"}, "is not configured"},
+		{"no keys", config.JWTConfig{}, `"default" is not configured`},
+		{"duplicate id", config.JWTConfig{Secret: testSecret, Keys: []config.JWTKey{{ID: LegacyKeyID, Algorithm: "HS256", Secret: "x"}}}, "defined twice"},
+		{"hs256 without secret", config.JWTConfig{Secret: testSecret, Keys: []config.JWTKey{{ID: "hs", Algorithm: "HS256"}}}, "need a secret"},
+		{"unsupported algorithm", config.JWTConfig{Secret: testSecret, Keys: []config.JWTKey{{ID: "es", Algorithm: "ES256", KeyFile: rsFile}}}, "unsupported algorithm"},
+		{"rsa file is not ed25519", config.JWTConfig{Secret: testSecret, Keys: []config.JWTKey{{ID: "ed", Algorithm: "EdDSA", KeyFile: rsFile}}}, "no Ed25519 key"},
+		{"ed25519 file is not rsa", config.JWTConfig{Secret: testSecret, Keys: []config.JWTKey{{ID: "rs", Algorithm: "RS256", KeyFile: edFile}}}, "no RSA key"},
+	}
+	for _, tt := range tests {
+		_, err := NewKeyring(tt.cfg)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestKeyringJWKS(t *testing.T) {
+	k := newTestKeys(t)
+	set := k.keyring(t, "rs").JWKS()
+
+	// HMAC keys are secrets and never published.
+	var ids []string
+	for _, key := range set.Keys {
+		ids = append(ids, key.Kid)
+	}
+	if got, want := strings.Join(ids, ","), "ed,ed-pub,rs,rs-pub"; got != want {
+		t.Fatalf("kids = %s, want %s", got, want)
+	}
+
+	decode := func(s string) []byte {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	want := map[string]interface{}{
+		"rs":     &k.rs.PublicKey,
+		"rs-pub": &k.rsVerify.PublicKey,
+		"ed":     k.ed.Public(),
+		"ed-pub": k.edVerify.Public(),
+	}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "sig" {
+			t.Errorf("%s: use = %q", jwk.Kid, jwk.Use)
+		}
+		switch pub := want[jwk.Kid].(type) {
+		case *rsa.PublicKey:
+			if jwk.Kty != "RSA" || jwk.Alg != "RS256" {
+				t.Errorf("%s: kty = %q, alg = %q", jwk.Kid, jwk.Kty, jwk.Alg)
+			}
+			n := new(big.Int).SetBytes(decode(jwk.N))
+			e := new(big.Int).SetBytes(decode(jwk.E))
+			if n.Cmp(pub.N) != 0 || e.Int64() != int64(pub.E) {
+				t.Errorf("%s: n or e does not match the key", jwk.Kid)
+			}
+		case ed25519.PublicKey:
+			if jwk.Kty != "OKP" || jwk.Crv != "Ed25519" || jwk.Alg != "EdDSA" {
+				t.Errorf("%s: kty = %q, crv = %q, alg = %q", jwk.Kid, jwk.Kty, jwk.Crv, jwk.Alg)
+			}
+			if !pub.Equal(ed25519.PublicKey(decode(jwk.X))) {
+				t.Errorf("%s: x does not match the key", jwk.Kid)
+			}
+		}
+	}
+}
@@ -1,15 +1,23 @@
 package utils
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"math/big"
 
+	"go.opentelemetry.io/otel"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string, error) {
+var tracer = otel.Tracer("github.com/ISKOnnect/iskonnect-web/internal/utils")
+
+func HashPassword(ctx context.Context, password string) (string, error) {
+	_, span := tracer.Start(ctx, "bcrypt.hash")
+	defer span.End()
+
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return "", fmt.Errorf("hash failed: %w", err)
@@ -29,6 +37,13 @@ func GenerateRandomToken(length int) (string, error) {
 	return hex.EncodeToString(b), nil
 }
 
+// HashToken returns the hex SHA-256 of a high-entropy token for storage.
+// Unlike passwords these need no slow hash; they can't be guessed.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func GenerateOTP(length int) (string, error) {
 	const digits = "0123456789"
 	result := make([]byte, length)